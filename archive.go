@@ -0,0 +1,101 @@
+package mdlinks
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TarFS reads the tar archive from r, like an uncompressed published
+// documentation bundle, and returns the in-memory filesystem of its regular
+// files and directories, so that the bundle can be checked exactly as
+// shipped. Other entries, like symbolic links, are skipped. For zip
+// archives, use the *zip.Reader from the archive/zip package, which is an
+// fs.FS already.
+func TarFS(r io.Reader) (fs.FS, error) {
+	fsys := tarFS{".": {info: skeletonInfo("."), dir: true}}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys[name] = &tarEntry{info: hdr.FileInfo(), dir: true}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			fsys[name] = &tarEntry{info: hdr.FileInfo(), data: data}
+		}
+	}
+	// add directories implied by paths of files, then list entries of all
+	// directories
+	for name := range fsys {
+		for dir := path.Dir(name); ; dir = path.Dir(dir) {
+			if _, ok := fsys[dir]; ok {
+				break
+			}
+			fsys[dir] = &tarEntry{info: skeletonInfo(path.Base(dir)), dir: true}
+		}
+	}
+	names := make([]string, 0, len(fsys))
+	for name := range fsys {
+		if name != "." {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if parent := fsys[path.Dir(name)]; parent.dir {
+			parent.entries = append(parent.entries, fs.FileInfoToDirEntry(fsys[name].info))
+		}
+	}
+	return fsys, nil
+}
+
+// tarFS is the in-memory filesystem built by TarFS, keyed by paths of its
+// files and directories.
+type tarFS map[string]*tarEntry
+
+// tarEntry is a file or a directory of tarFS.
+type tarEntry struct {
+	info    fs.FileInfo
+	dir     bool
+	data    []byte        // for files
+	entries []fs.DirEntry // for directories, sorted by name
+}
+
+func (t tarFS) Open(name string) (fs.File, error) {
+	e, ok := t[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if e.dir {
+		return &dirFile{info: e.info, entries: e.entries}, nil
+	}
+	return &tarFile{Reader: bytes.NewReader(e.data), info: e.info}, nil
+}
+
+// tarFile is the open file of tarFS.
+type tarFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *tarFile) Close() error               { return nil }
@@ -0,0 +1,201 @@
+package mdlinks
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// isAsciiDoc reports whether p is an AsciiDoc document. AsciiDoc documents
+// matched by Checker.Matcher are parsed with extractAsciiDocDetails instead
+// of the markdown parser.
+func isAsciiDoc(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".adoc", ".asciidoc", ".asc":
+		return true
+	}
+	return false
+}
+
+var (
+	// adocSectionRe matches section titles, like “== Getting Started”.
+	adocSectionRe = regexp.MustCompile(`^(={1,6})[ \t]+(\S.*?)[ \t]*$`)
+
+	// adocBlockAnchorRe matches block anchors on their own lines, like
+	// “[[install]]”, “[[install,Installation]]”, or “[#install.role]”.
+	adocBlockAnchorRe = regexp.MustCompile(`^(?:\[\[([A-Za-z_:][\w:.-]*)(?:,[^\]]*)?\]\]|\[#([A-Za-z_:][\w:.-]*)[^\]]*\])$`)
+
+	// adocInlineAnchorRe matches inline anchors, like “[[id]]” or
+	// “anchor:id[]”.
+	adocInlineAnchorRe = regexp.MustCompile(`\[\[([A-Za-z_:][\w:.-]*)(?:,[^\]]*)?\]\]|\banchor:([A-Za-z_:][\w:.-]*)\[`)
+
+	// adocXrefRe matches cross references, like “xref:file.adoc#id[text]”
+	// or “<<file.adoc#id,text>>”.
+	adocXrefRe = regexp.MustCompile(`\bxref:([^\s\[\]]+)\[|<<([^\s<>,]+)(?:,[^>]*)?>>`)
+
+	// adocMacroRe matches macros with targets, like “link:file.pdf[]”,
+	// “image::diagram.png[]”, or “include::part.adoc[]”.
+	adocMacroRe = regexp.MustCompile(`\b(?:link|image|include|video|audio)::?([^\s\[\]]+)\[`)
+
+	// adocAttributeRe matches attribute entries configuring generated
+	// section ids.
+	adocAttributeRe = regexp.MustCompile(`^:(idprefix|idseparator):[ \t]*(.*?)[ \t]*$`)
+
+	// adocDelimiterRe matches delimiters of listing, literal, comment, and
+	// passthrough blocks, which contents is not parsed.
+	adocDelimiterRe = regexp.MustCompile(`^(?:-{4,}|\.{4,}|/{4,}|\+{4,}|` + "`{3,}" + `)$`)
+)
+
+// extractAsciiDocDetails parses AsciiDoc document body, returning its cross
+// references, link, image, and include macro targets, and section anchors.
+// Section ids are generated the way Asciidoctor does it, respecting the
+// idprefix and idseparator document attributes.
+func extractAsciiDocDetails(body []byte) *docDetails {
+	d := &docDetails{anchors: make(map[string]struct{})}
+	prefix, sep := "_", "_"
+	addAnchor := func(id string, off, level int, text string) {
+		d.anchors[id] = struct{}{}
+		a := Anchor{ID: id, Level: level, Text: text, Offset: off}
+		a.Line, a.Column = textPosition(body, off)
+		d.anchorList = append(d.anchorList, a)
+	}
+	addLink := func(raw, p, fragment string, off int) {
+		l := LinkInfo{Raw: raw}
+		l.setOffset(body, off)
+		l.LineStart, l.LineEnd = l.Line, l.Line
+		switch {
+		case isExternalLink(raw):
+			d.external = append(d.external, l)
+		case localLink(raw) != nil:
+			l.Path, l.Fragment = p, fragment
+			d.links = append(d.links, l)
+		}
+		d.all = append(d.all, l)
+	}
+	var delim string       // delimiter of the current verbatim block
+	var blockAnchor string // anchor of the next block
+	for off := 0; off < len(body); {
+		line := body[off:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		lineOff := off
+		off += len(line) + 1
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if m := adocDelimiterRe.Find(line); m != nil {
+			switch {
+			case delim == "":
+				delim = string(m)
+			case delim == string(m):
+				delim = ""
+			}
+			continue
+		}
+		if delim != "" || bytes.HasPrefix(line, []byte("//")) {
+			continue
+		}
+		if m := adocAttributeRe.FindSubmatch(line); m != nil {
+			if string(m[1]) == "idprefix" {
+				prefix = string(m[2])
+			} else {
+				sep = string(m[2])
+			}
+			continue
+		}
+		if m := adocBlockAnchorRe.FindSubmatchIndex(line); m != nil {
+			i := 2
+			if m[2] < 0 {
+				i = 4
+			}
+			blockAnchor = string(line[m[i]:m[i+1]])
+			addAnchor(blockAnchor, lineOff+m[i], 0, "")
+			continue
+		}
+		if m := adocSectionRe.FindSubmatchIndex(line); m != nil {
+			title := string(line[m[4]:m[5]])
+			level := m[3] - m[2]
+			if blockAnchor != "" {
+				// explicit anchor replaces the generated id
+				a := &d.anchorList[len(d.anchorList)-1]
+				a.Level, a.Text = level, title
+			} else if id := asciidocID(title, prefix, sep); id != "" {
+				base := id
+				for n := 2; ; n++ {
+					if _, ok := d.anchors[id]; !ok {
+						break
+					}
+					id = base + sep + strconv.Itoa(n)
+				}
+				addAnchor(id, lineOff, level, title)
+			}
+		}
+		blockAnchor = ""
+		for _, m := range adocInlineAnchorRe.FindAllSubmatchIndex(line, -1) {
+			i := 2
+			if m[2] < 0 {
+				i = 4
+			}
+			addAnchor(string(line[m[i]:m[i+1]]), lineOff+m[i], 0, "")
+		}
+		for _, m := range adocXrefRe.FindAllSubmatchIndex(line, -1) {
+			i := 2
+			if m[2] < 0 {
+				i = 4
+			}
+			raw := string(line[m[i]:m[i+1]])
+			if strings.Contains(raw, "{") { // attribute reference
+				continue
+			}
+			p, fragment, ok := strings.Cut(raw, "#")
+			switch {
+			case !ok && !isAsciiDoc(p):
+				p, fragment = "", raw // reference to an anchor in the same document
+			case p != "" && path.Ext(p) == "":
+				p += ".adoc"
+			}
+			addLink(raw, p, fragment, lineOff+m[i])
+		}
+		for _, m := range adocMacroRe.FindAllSubmatchIndex(line, -1) {
+			raw := string(line[m[2]:m[3]])
+			if strings.Contains(raw, "{") {
+				continue
+			}
+			var p, fragment string
+			if u := localLink(raw); u != nil {
+				p, fragment = u.Path, u.Fragment
+			}
+			addLink(raw, p, fragment, lineOff+m[2])
+		}
+	}
+	return d
+}
+
+// asciidocID generates the id of the section title the way Asciidoctor does
+// it: the lowercased title, with characters other than letters, digits,
+// underscores, hyphens, dots, and spaces removed, and spaces, dots, and
+// hyphens replaced with sep.
+func asciidocID(title, prefix, sep string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	var inSep bool // last written characters are sep
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == ' ' || r == '.' || r == '-':
+			if !inSep {
+				b.WriteString(sep)
+				inSep = true
+			}
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			b.WriteRune(r)
+			inSep = false
+		}
+	}
+	id := strings.TrimSuffix(b.String(), sep)
+	if prefix == "" {
+		id = strings.TrimPrefix(id, sep)
+	}
+	return id
+}
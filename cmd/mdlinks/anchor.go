@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runRenameAnchor implements the “rename-anchor” subcommand: it rewrites
+// links pointing to an anchor of a document to point to another one.
+func runRenameAnchor(args []string) error {
+	fset := flag.NewFlagSet("mdlinks rename-anchor", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks rename-anchor [flags] file old-slug new-slug\n\n"+
+			"Rewrites links to the old-slug anchor of the file to point to new-slug,\n"+
+			"like after the file's header was changed.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	dryRun := fset.Bool("n", false, "only print link changes, don't modify files")
+	fset.Parse(args)
+	if fset.NArg() != 3 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	file, err := dirRelative(opts.dir, fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	oldSlug := strings.TrimPrefix(fset.Arg(1), "#")
+	newSlug := strings.TrimPrefix(fset.Arg(2), "#")
+	if oldSlug == "" || newSlug == "" {
+		return fmt.Errorf("slugs must not be empty")
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	f := &mdlinks.Fixer{WriteFile: opts.writeFile(*dryRun)}
+	fixes, err := f.RenameAnchor(c, fsys, filepath.ToSlash(file), oldSlug, newSlug)
+	printFixes(fixes)
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runBacklinks implements the “backlinks” subcommand: it lists links to the
+// given files.
+func runBacklinks(args []string) error {
+	fset := flag.NewFlagSet("mdlinks backlinks", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks backlinks [flags] file...\n\n"+
+			"Lists documents and lines linking to the given files.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	fset.Parse(args)
+	if fset.NArg() == 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	var files []string
+	for _, arg := range fset.Args() {
+		p, err := dirRelative(opts.dir, arg)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(p))
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	index, err := c.Backlinks(fsys)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		for _, e := range index[file] {
+			if len(files) > 1 {
+				fmt.Printf("%s: ", file)
+			}
+			fmt.Printf("%s:%d: %s\n", e.From, e.Link.LineStart, e.Link.Raw)
+		}
+	}
+	return nil
+}
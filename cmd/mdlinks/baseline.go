@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/artyom/mdlinks"
+)
+
+// baselineEntry identifies a grandfathered broken link. Line numbers are
+// deliberately not part of it, so that unrelated edits moving the link
+// around don't make it reappear.
+type baselineEntry struct {
+	File string `json:"file"`
+	Link string `json:"link"`
+	Kind string `json:"kind"`
+}
+
+func baselineKey(l mdlinks.BrokenLink) baselineEntry {
+	return baselineEntry{File: l.File, Link: l.Link.Raw, Kind: l.Kind()}
+}
+
+// applyBaseline filters out broken links recorded in the baseline file name.
+// If the file does not exist, it is created with all links, and no links are
+// returned.
+func applyBaseline(name string, links []mdlinks.BrokenLink) ([]mdlinks.BrokenLink, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		entries := make([]baselineEntry, 0, len(links))
+		for _, l := range links {
+			entries = append(entries, baselineKey(l))
+		}
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return nil, os.WriteFile(name, append(b, '\n'), 0666)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []baselineEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	// count entries, so that a new copy of an already grandfathered link
+	// is still reported
+	known := make(map[baselineEntry]int, len(entries))
+	for _, e := range entries {
+		known[e]++
+	}
+	var out []mdlinks.BrokenLink
+	for _, l := range links {
+		k := baselineKey(l)
+		if known[k] > 0 {
+			known[k]--
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
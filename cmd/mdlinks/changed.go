@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// checkChanged checks documents of fsys, stored in the git working tree at
+// dir, affected by changes since the merge base with the git ref base, and
+// returns their paths along with broken links found.
+func checkChanged(dir, base string, c *mdlinks.Checker, fsys fs.FS) ([]string, []mdlinks.BrokenLink, error) {
+	changed, err := changedFiles(dir, base)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := affectedDocuments(c, fsys, changed)
+	if err != nil {
+		return nil, nil, err
+	}
+	links, err := c.CheckFiles(fsys, files...)
+	return files, links, err
+}
+
+// affectedDocuments returns documents of fsys to check after the changed
+// files were changed or deleted: the changed documents, and documents
+// linking to any of changed files.
+func affectedDocuments(c *mdlinks.Checker, fsys fs.FS, changed []string) ([]string, error) {
+	var out []string
+	for _, p := range changed {
+		if _, err := fs.Stat(fsys, p); err == nil {
+			out = append(out, p)
+		}
+	}
+	out, err := checkedFiles(c, out)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, p := range out {
+		seen[p] = true
+	}
+	targets := make(map[string]bool)
+	for _, p := range changed {
+		targets[p] = true
+	}
+	docs, err := c.Links(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, fl := range docs {
+		if seen[fl.File] {
+			continue
+		}
+		for _, l := range fl.Links {
+			if !l.External && targets[l.Target] {
+				seen[fl.File] = true
+				out = append(out, fl.File)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// changedFiles returns /-separated paths, relative to dir, of files changed in
+// the git working tree at dir since its merge base with the git ref base,
+// including uncommitted, untracked, and deleted ones.
+func changedFiles(dir, base string) ([]string, error) {
+	mergeBase, err := git(dir, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := git(dir, "diff", "--name-only", "--relative", "--no-renames", "-z", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, p := range strings.Split(diff+untracked, "\x00") {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// git runs git with args in dir, and returns its output.
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+type ccIssue struct {
+	Description string     `json:"description"`
+	CheckName   string     `json:"check_name"`
+	Fingerprint string     `json:"fingerprint"`
+	Severity    string     `json:"severity"`
+	Location    ccLocation `json:"location"`
+}
+
+type ccLocation struct {
+	Path  string  `json:"path"`
+	Lines ccLines `json:"lines"`
+}
+
+type ccLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+// reportCodeClimate writes broken links as a GitLab Code Quality report.
+// Paths are relative to the current directory, usually the repository root,
+// so that GitLab can place issues in merge request diffs.
+func reportCodeClimate(w io.Writer, res *result) error {
+	out := make([]ccIssue, 0, len(res.links))
+	seen := make(map[string]int)
+	for _, l := range res.links {
+		// fingerprint deliberately excludes line numbers, so that the same
+		// issue is recognized across commits that shift lines around;
+		// repeated identical links are told apart by their occurrence number
+		key := l.File + "\x00" + l.Kind() + "\x00" + l.Link.Raw
+		seen[key]++
+		sum := md5.Sum([]byte(fmt.Sprintf("%s\x00%d", key, seen[key])))
+		lines := ccLines{Begin: l.Link.LineStart, End: l.Link.LineEnd}
+		if lines.Begin == 0 {
+			lines = ccLines{Begin: 1}
+		}
+		sev := "major"
+		if l.IsWarning() {
+			sev = "minor"
+		}
+		out = append(out, ccIssue{
+			Description: l.String(),
+			CheckName:   l.Kind(),
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    sev,
+			Location:    ccLocation{Path: filepath.ToSlash(filepath.Join(res.dir, l.File)), Lines: lines},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
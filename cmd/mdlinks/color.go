@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// palette decorates parts of the text report with ANSI escape sequences if
+// it's true, and leaves them as is otherwise.
+type palette bool
+
+func (p palette) paint(code, s string) string {
+	if !p || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p palette) bold(s string) string   { return p.paint("1", s) }
+func (p palette) dim(s string) string    { return p.paint("2", s) }
+func (p palette) red(s string) string    { return p.paint("31", s) }
+func (p palette) green(s string) string  { return p.paint("32", s) }
+func (p palette) yellow(s string) string { return p.paint("33", s) }
+
+// message returns the description of l with the file path in bold, broken
+// destination in red, and the rest of the text dimmed.
+func (p palette) message(l mdlinks.BrokenLink) string {
+	if !p {
+		return l.String()
+	}
+	msg := l.Message()
+	var suggestion string
+	if l.Suggestion != "" {
+		msg = strings.TrimSuffix(msg, fmt.Sprintf("; did you mean %q?", l.Suggestion))
+		suggestion = p.dim("; did you mean ") + p.green(strconv.Quote(l.Suggestion)) + p.dim("?")
+	}
+	dest := strconv.Quote(l.Link.Raw)
+	if !strings.Contains(msg, dest) {
+		dest = "[" + l.Link.Raw + "]" // reference labels
+	}
+	if i := strings.Index(msg, dest); l.Link.Raw != "" && i >= 0 {
+		msg = p.dim(msg[:i]) + p.red(dest) + p.dim(msg[i+len(dest):])
+	} else {
+		msg = p.dim(msg)
+	}
+	file := l.File
+	if l.Link.Cell != 0 {
+		file = fmt.Sprintf("%s (cell %d)", l.File, l.Link.Cell)
+	}
+	return p.bold(file) + ": " + msg + suggestion
+}
+
+// reportColorText is like reportText, but colors the output.
+func reportColorText(_ io.Writer, res *result) error { return writeText(res, true) }
+
+// colorOutput reports whether the text report should be colored for the
+// -color flag mode: "always", "never", or "auto", which enables colors if
+// stderr is a terminal and the NO_COLOR environment variable is not set.
+func colorOutput(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		f, ok := log.Writer().(*os.File)
+		if !ok {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("unsupported -color value %q, supported values are: auto, always, never", mode)
+}
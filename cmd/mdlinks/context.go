@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/artyom/mdlinks"
+)
+
+// sources reads documents with broken links to show their source lines.
+type sources struct {
+	fsys  fs.FS
+	files map[string][]byte // nil value if file can't be read
+}
+
+func newSources(fsys fs.FS) *sources {
+	return &sources{fsys: fsys, files: make(map[string][]byte)}
+}
+
+// snippet returns the source line of the broken link l, prefixed with its
+// number, and the line with a caret under the link destination, like:
+//
+//	3 | See [docs](guide.md) for details.
+//	  |            ^~~~~~~~
+//
+// The caret is colored with p. It returns an empty string if the position of
+// l is unknown, or is relative to a notebook cell.
+func (s *sources) snippet(l mdlinks.BrokenLink, p palette) string {
+	if l.Link.Line == 0 || l.Link.Cell != 0 {
+		return ""
+	}
+	body, ok := s.files[l.File]
+	if !ok {
+		body, _ = fs.ReadFile(s.fsys, l.File)
+		s.files[l.File] = body
+	}
+	off := l.Link.Offset
+	if off > len(body) || off < l.Link.Column-1 {
+		return ""
+	}
+	start := off - (l.Link.Column - 1)
+	end := len(body)
+	if i := bytes.IndexByte(body[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	line := strings.TrimSuffix(string(body[start:end]), "\r")
+	if off > start+len(line) {
+		return ""
+	}
+	// keep tabs, so that the caret is aligned the same way as the text
+	pad := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, line[:off-start])
+	width := 1
+	if l.Link.Raw != "" && strings.HasPrefix(line[off-start:], l.Link.Raw) {
+		width = utf8.RuneCountInString(l.Link.Raw)
+	}
+	num := strconv.Itoa(l.Link.Line)
+	gutter := strings.Repeat(" ", len(num))
+	return fmt.Sprintf(" %s | %s\n %s | %s%s\n", num, line, gutter, pad, p.red("^"+strings.Repeat("~", width-1)))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runFix implements the “fix” subcommand: it checks the tree and rewrites
+// broken links that have unambiguous suggestions in place.
+func runFix(args []string) error {
+	fset := flag.NewFlagSet("mdlinks fix", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks fix [flags]\n\n"+
+			"Rewrites broken links that have unambiguous fixes: case mismatches,\n"+
+			"files found under the same or similar name elsewhere, fragment typos.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	dryRun := fset.Bool("n", false, "only print fixes, don't modify files")
+	fset.Parse(args)
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	links, err := brokenLinks(fsys, c)
+	if err != nil {
+		return err
+	}
+	f := &mdlinks.Fixer{WriteFile: opts.writeFile(*dryRun)}
+	fixes, err := f.Fix(fsys, links)
+	printFixes(fixes)
+	if err != nil {
+		return err
+	}
+	fixed := make(map[string]bool)
+	for _, fx := range fixes {
+		fixed[fixKey(fx.File, fx.Line, fx.Old)] = true
+	}
+	var remaining int
+	for _, l := range links {
+		if fixed[fixKey(l.File, l.Link.LineStart, l.Link.Raw)] {
+			continue
+		}
+		if l.IsWarning() {
+			log.Println("warning:", l)
+			continue
+		}
+		log.Println(l)
+		remaining++
+	}
+	log.Printf("fixed %d link(s), %d broken link(s) left", len(fixes), remaining)
+	if remaining != 0 {
+		os.Exit(127)
+	}
+	return nil
+}
+
+func fixKey(file string, line int, raw string) string {
+	return fmt.Sprintf("%s\x00%d\x00%s", file, line, raw)
+}
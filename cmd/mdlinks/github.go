@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artyom/mdlinks"
+)
+
+// githubAnnotations emits workflow commands so that GitHub shows broken links
+// as annotations on the changed files; paths of files are relative to dir.
+// Broken links of the same file line are reported as a single annotation, as
+// GitHub limits the number of annotations per step.
+func githubAnnotations(dir string, links []mdlinks.BrokenLink) {
+	type annotation struct {
+		cmd, file     string
+		line, endLine int
+		col, endCol   int
+		links         []mdlinks.BrokenLink
+	}
+	type key struct {
+		cmd, file string
+		line      int
+	}
+	var out []*annotation
+	seen := make(map[key]*annotation)
+	for _, l := range links {
+		cmd := "error"
+		if l.IsWarning() {
+			cmd = "warning"
+		}
+		a := &annotation{cmd: cmd, file: filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(l.File)))}
+		switch {
+		case l.Link.Cell != 0: // positions are relative to the notebook cell
+		case l.Link.Line != 0:
+			a.line, a.endLine = l.Link.Line, l.Link.Line
+			a.col, a.endCol = l.Link.Column, l.Link.Column+len(l.Link.Raw)
+		default:
+			a.line, a.endLine = l.Link.LineStart, l.Link.LineEnd
+		}
+		k := key{cmd: cmd, file: l.File, line: a.line}
+		if prev, ok := seen[k]; ok && a.line != 0 {
+			if a.endLine > prev.endLine {
+				prev.endLine = a.endLine
+			}
+			if a.col == 0 || prev.col == 0 {
+				prev.col, prev.endCol = 0, 0
+			} else if a.endCol > prev.endCol {
+				prev.endCol = a.endCol
+			}
+			prev.links = append(prev.links, l)
+			continue
+		}
+		a.links = []mdlinks.BrokenLink{l}
+		seen[k] = a
+		out = append(out, a)
+	}
+	for _, a := range out {
+		// https://docs.github.com/en/actions/learn-github-actions/workflow-commands-for-github-actions#setting-an-error-message
+		// ::error file={name},line={line},endLine={endLine},col={col},endColumn={endColumn},title={title}::{message}
+		props := "file=" + escapeProperty(a.file)
+		if a.line != 0 {
+			props += fmt.Sprintf(",line=%d,endLine=%d", a.line, a.endLine)
+		}
+		if a.col != 0 && a.line == a.endLine {
+			props += fmt.Sprintf(",col=%d,endColumn=%d", a.col, a.endCol)
+		}
+		title := a.links[0].Reason()
+		if len(a.links) > 1 {
+			title = fmt.Sprintf("%d broken links", len(a.links))
+		}
+		msgs := make([]string, len(a.links))
+		for i, l := range a.links {
+			msgs[i] = l.String()
+		}
+		log.Printf("::%s %s,title=%s::%s", a.cmd, props, escapeProperty(title), escapeData(strings.Join(msgs, "\n")))
+	}
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// reportGitHubReview posts broken links as a pull request review, with
+// comments on changed lines of the pull request diff; broken links on other
+// lines are listed in the review body. It must run in a GitHub Actions
+// workflow triggered by a pull_request event, with the GITHUB_TOKEN
+// environment variable set to a token allowed to write pull requests. Links
+// are also written to w in the short format.
+func reportGitHubReview(w io.Writer, res *result) error {
+	if err := reportShort(w, res); err != nil {
+		return err
+	}
+	if len(res.links) == 0 {
+		return nil
+	}
+	pr, err := githubPullRequest()
+	if err != nil {
+		return err
+	}
+	changed, err := pr.changedLines()
+	if err != nil {
+		return err
+	}
+	type comment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	review := struct {
+		CommitID string    `json:"commit_id"`
+		Event    string    `json:"event"`
+		Body     string    `json:"body"`
+		Comments []comment `json:"comments,omitempty"`
+	}{CommitID: pr.head, Event: "COMMENT"}
+	var rest []string
+	for _, l := range res.links {
+		name := path.Clean(path.Join(filepath.ToSlash(res.dir), l.File))
+		line := l.Link.Line
+		if line == 0 {
+			line = l.Link.LineStart
+		}
+		if l.Link.Cell != 0 { // positions are relative to the notebook cell
+			line = 0
+		}
+		msg := cellLocation(l) + l.Message()
+		if l.IsWarning() {
+			msg = "warning: " + msg
+		}
+		if line != 0 && changed[name][line] {
+			review.Comments = append(review.Comments, comment{Path: name, Line: line, Side: "RIGHT", Body: msg})
+			continue
+		}
+		if line != 0 {
+			name = fmt.Sprintf("%s:%d", name, line)
+		}
+		rest = append(rest, fmt.Sprintf("* `%s`: %s", name, msg))
+	}
+	review.Body = fmt.Sprintf("mdlinks found %d broken links.", len(res.links))
+	if len(rest) != 0 {
+		review.Body += " Links outside of the changed lines:\n\n" + strings.Join(rest, "\n")
+	}
+	return pr.call(http.MethodPost, "/reviews", review, nil)
+}
+
+// githubPR describes a pull request the workflow runs for.
+type githubPR struct {
+	api    string // API URL of the pull request
+	token  string
+	head   string // SHA of the head commit
+	client *http.Client
+}
+
+// githubPullRequest returns the pull request of the pull_request event
+// triggering the GitHub Actions workflow run.
+func githubPullRequest() (*githubPR, error) {
+	token, repo, eventPath := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_EVENT_PATH")
+	if token == "" || repo == "" || eventPath == "" {
+		return nil, errors.New("github-review format needs GITHUB_TOKEN, GITHUB_REPOSITORY, and GITHUB_EVENT_PATH environment variables")
+	}
+	b, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, err
+	}
+	var event struct {
+		PullRequest *struct {
+			Number int `json:"number"`
+			Head   struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, fmt.Errorf("reading GitHub event: %w", err)
+	}
+	if event.PullRequest == nil {
+		return nil, errors.New("github-review format only works for pull_request events")
+	}
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	return &githubPR{
+		api:    fmt.Sprintf("%s/repos/%s/pulls/%d", strings.TrimSuffix(api, "/"), repo, event.PullRequest.Number),
+		token:  token,
+		head:   event.PullRequest.Head.SHA,
+		client: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// changedLines returns line numbers of the new versions of files, which can
+// have review comments, keyed by file paths.
+func (pr *githubPR) changedLines() (map[string]map[int]bool, error) {
+	out := make(map[string]map[int]bool)
+	const perPage = 100
+	for page := 1; ; page++ {
+		var files []struct {
+			Name  string `json:"filename"`
+			Patch string `json:"patch"`
+		}
+		if err := pr.call(http.MethodGet, fmt.Sprintf("/files?per_page=%d&page=%d", perPage, page), nil, &files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			out[f.Name] = diffLines(f.Patch)
+		}
+		if len(files) < perPage {
+			return out, nil
+		}
+	}
+}
+
+// diffLines returns numbers of lines of the new file version that are added
+// or kept as context in the unified diff patch.
+func diffLines(patch string) map[int]bool {
+	out := make(map[int]bool)
+	var line int
+	for _, s := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(s, "@@"):
+			// @@ -l,s +l,s @@
+			var oldRange string
+			if _, err := fmt.Sscanf(s, "@@ %s +%d", &oldRange, &line); err != nil {
+				line = 0
+			}
+		case line == 0 || strings.HasPrefix(s, "-") || strings.HasPrefix(s, `\`):
+		default:
+			out[line] = true
+			line++
+		}
+	}
+	return out
+}
+
+// call sends a request to the pull request API URL with the suffix, encoding
+// body as JSON, if not nil, and decoding the response into out, if not nil.
+func (pr *githubPR) call(method, suffix string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, pr.api+suffix, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+pr.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := pr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("GitHub API %s %s: %s: %s", method, req.URL.Path, resp.Status, bytes.TrimSpace(b))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
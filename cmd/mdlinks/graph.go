@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runGraph implements the “graph” subcommand: it writes the graph of links
+// between local files to stdout.
+func runGraph(args []string) error {
+	fset := flag.NewFlagSet("mdlinks graph", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks graph [flags]\n\n"+
+			"Writes the graph of links between documents and other local files.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	format := fset.String("format", "dot", "output `format`: dot or json")
+	fset.Parse(args)
+	var write func(io.Writer, *mdlinks.Graph) error
+	switch *format {
+	case "dot":
+		write = writeDOT
+	case "json":
+		write = writeGraphJSON
+	default:
+		return fmt.Errorf("unsupported -format value %q, supported values are: dot, json", *format)
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	g, err := c.Graph(fsys)
+	if err != nil {
+		return err
+	}
+	return write(os.Stdout, g)
+}
+
+// writeDOT writes g in the Graphviz DOT format, with one edge per pair of
+// files labeled with line numbers of the links.
+func writeDOT(w io.Writer, g *mdlinks.Graph) error {
+	type pair struct{ from, to string }
+	var pairs []pair
+	lines := make(map[pair][]string)
+	for _, e := range g.Edges {
+		k := pair{e.From, e.To}
+		if _, ok := lines[k]; !ok {
+			pairs = append(pairs, k)
+		}
+		if e.Link.LineStart != 0 {
+			lines[k] = append(lines[k], strconv.Itoa(e.Link.LineStart))
+		} else {
+			lines[k] = append(lines[k], "?")
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph mdlinks {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+	seen := make(map[string]bool)
+	for _, f := range g.Files {
+		seen[f] = true
+		fmt.Fprintf(bw, "\t%s;\n", strconv.Quote(f))
+	}
+	var others []string // non-document targets, like images
+	for _, e := range g.Edges {
+		if !seen[e.To] {
+			seen[e.To] = true
+			others = append(others, e.To)
+		}
+	}
+	sort.Strings(others)
+	for _, f := range others {
+		fmt.Fprintf(bw, "\t%s [shape=ellipse];\n", strconv.Quote(f))
+	}
+	for _, k := range pairs {
+		fmt.Fprintf(bw, "\t%s -> %s [label=%s];\n", strconv.Quote(k.from), strconv.Quote(k.to),
+			strconv.Quote(strings.Join(lines[k], ",")))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	Path     string `json:"path"`
+	Document bool   `json:"document"` // whether the file was matched by -pat
+}
+
+type jsonEdge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Link      string `json:"link"`
+	LineStart int    `json:"lineStart,omitempty"`
+	LineEnd   int    `json:"lineEnd,omitempty"`
+}
+
+func writeGraphJSON(w io.Writer, g *mdlinks.Graph) error {
+	out := jsonGraph{Nodes: make([]jsonNode, 0, len(g.Files)), Edges: make([]jsonEdge, 0, len(g.Edges))}
+	seen := make(map[string]bool)
+	for _, f := range g.Files {
+		seen[f] = true
+		out.Nodes = append(out.Nodes, jsonNode{Path: f, Document: true})
+	}
+	for _, e := range g.Edges {
+		if !seen[e.To] {
+			seen[e.To] = true
+			out.Nodes = append(out.Nodes, jsonNode{Path: e.To})
+		}
+		out.Edges = append(out.Edges, jsonEdge{
+			From:      e.From,
+			To:        e.To,
+			Link:      e.Link.Raw,
+			LineStart: e.Link.LineStart,
+			LineEnd:   e.Link.LineEnd,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runHook implements the “hook” subcommand: “hook install” installs the git
+// pre-commit hook checking staged documents.
+func runHook(args []string) error {
+	fset := flag.NewFlagSet("mdlinks hook install", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks hook install [-f] [check flags]\n\n"+
+			"Installs the git pre-commit hook running “mdlinks -staged” with the given check flags,\n"+
+			"like -dir or -pat, so that commits with broken links are blocked.\n"+
+			"Pass -f to overwrite the existing pre-commit hook.")
+	}
+	if len(args) == 0 || args[0] != "install" {
+		fset.Usage()
+		os.Exit(2)
+	}
+	// flags following -f are the check ones, passed to the hook as is
+	args = args[1:]
+	force := len(args) != 0 && args[0] == "-f"
+	if force {
+		args = args[1:]
+	}
+	name, err := git(".", "rev-parse", "--git-path", "hooks/pre-commit")
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if _, err := os.Stat(name); err == nil && !force {
+		return fmt.Errorf("%s already exists; pass -f to overwrite it", name)
+	}
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# installed by “mdlinks hook install”\nexec mdlinks -staged")
+	for _, arg := range args {
+		b.WriteString(" '" + strings.ReplaceAll(arg, "'", `'\''`) + "'")
+	}
+	b.WriteString("\n")
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(name, []byte(b.String()), 0o755); err != nil {
+		return err
+	}
+	fmt.Println("installed", name)
+	return nil
+}
+
+// checkStaged checks documents of the directory dir of the git working tree
+// affected by staged changes, reading their content from the git index. It
+// returns the staged filesystem, paths of checked documents, and broken links
+// found.
+func checkStaged(dir string, c *mdlinks.Checker) (fs.FS, []string, []mdlinks.BrokenLink, error) {
+	fsys, err := stagedFS(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	staged, err := stagedFiles(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	files, err := affectedDocuments(c, fsys, staged)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	links, err := c.CheckFiles(fsys, files...)
+	return fsys, files, links, err
+}
+
+// stagedFS returns the filesystem of the directory dir of the git working
+// tree as it's staged in the git index.
+func stagedFS(dir string) (fs.FS, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	top, prefix, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	args := []string{"write-tree"}
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		args = append(args, "--prefix="+prefix)
+	}
+	tree, err := git(top, args...)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "archive", "--format=tar", strings.TrimSpace(tree))
+	cmd.Dir = top
+	cmd.Stderr = &stderr
+	archive, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git archive: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return mdlinks.TarFS(bytes.NewReader(archive))
+}
+
+// stagedFiles returns /-separated paths, relative to dir, of files staged in
+// the git index of the working tree at dir, including deleted ones.
+func stagedFiles(dir string) ([]string, error) {
+	out, err := git(dir, "diff", "--cached", "--name-only", "--relative", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(out, "\x00"), "\x00"), nil
+}
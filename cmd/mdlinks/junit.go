@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"` // warnings
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// reportJUnit writes a JUnit-compatible XML report with one test case per
+// checked file; files with broken links are reported as failed test cases.
+func reportJUnit(w io.Writer, res *result) error {
+	suite := junitSuite{Name: "mdlinks"}
+	for _, fl := range res.perFile() {
+		file, links := fl.file, fl.links
+		tc := junitCase{Name: file, Classname: "mdlinks", File: file}
+		var errs, warns strings.Builder
+		var numErrs int
+		for _, l := range links {
+			b := &errs
+			if l.IsWarning() {
+				b = &warns
+			} else {
+				numErrs++
+			}
+			if l.Link.LineStart != 0 {
+				fmt.Fprintf(b, "line %d: ", l.Link.LineStart)
+			}
+			fmt.Fprintln(b, l)
+		}
+		if numErrs != 0 {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d broken link(s)", numErrs),
+				Type:    "BrokenLinks",
+				Text:    errs.String(),
+			}
+			suite.Failures++
+		}
+		tc.SystemOut = warns.String()
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	doc := junitSuites{Tests: suite.Tests, Failures: suite.Failures, Suites: []junitSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
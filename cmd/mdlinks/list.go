@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runList implements the “list” subcommand: it lists links of every
+// document along with their resolved targets.
+func runList(args []string) error {
+	fset := flag.NewFlagSet("mdlinks list", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks list [flags]\n\n"+
+			"Lists links of every document, with their resolved targets;\n"+
+			"targets that don't exist are marked as missing.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	format := fset.String("format", "text", "output `format`: text or json")
+	local := fset.Bool("local", false, "only list local links")
+	fset.Parse(args)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported -format value %q, supported values are: json, text", *format)
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	docs, err := c.Links(fsys)
+	if err != nil {
+		return err
+	}
+	type jsonListLink struct {
+		File     string `json:"file"`
+		Link     string `json:"link"`
+		Target   string `json:"target,omitempty"`
+		External bool   `json:"external,omitempty"`
+		Exists   bool   `json:"exists"`
+		Line     int    `json:"line,omitempty"`
+		Column   int    `json:"column,omitempty"`
+		Cell     int    `json:"cell,omitempty"`
+	}
+	out := []jsonListLink{}
+	for _, fl := range docs {
+		for _, l := range fl.Links {
+			if *local && l.External {
+				continue
+			}
+			if *format == "json" {
+				out = append(out, jsonListLink{
+					File:     fl.File,
+					Link:     l.Raw,
+					Target:   l.Target,
+					External: l.External,
+					Exists:   l.Exists,
+					Line:     l.Line,
+					Column:   l.Column,
+					Cell:     l.Cell,
+				})
+				continue
+			}
+			fmt.Printf("%s:%d:%d: %s", fl.File, l.Line, l.Column, l.Raw)
+			switch {
+			case l.External:
+			case l.Exists:
+				fmt.Printf(" -> %s", l.Target)
+			default:
+				fmt.Printf(" -> %s (missing)", l.Target)
+			}
+			fmt.Println()
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	return nil
+}
+
+// runAnchors implements the “anchors” subcommand: it lists anchors of the
+// given documents, which links to them can use as fragments.
+func runAnchors(args []string) error {
+	fset := flag.NewFlagSet("mdlinks anchors", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks anchors [flags] file...\n\n"+
+			"Lists anchors of the given documents: header slugs and ids of HTML elements,\n"+
+			"generated the same way links are checked.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	format := fset.String("format", "text", "output `format`: text or json")
+	fset.Parse(args)
+	if fset.NArg() == 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported -format value %q, supported values are: json, text", *format)
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	type jsonAnchor struct {
+		File   string `json:"file"`
+		ID     string `json:"id"`
+		Level  int    `json:"level,omitempty"`
+		Text   string `json:"text,omitempty"`
+		Line   int    `json:"line,omitempty"`
+		Column int    `json:"column,omitempty"`
+		Cell   int    `json:"cell,omitempty"`
+	}
+	out := []jsonAnchor{}
+	for _, arg := range fset.Args() {
+		p, err := dirRelative(opts.dir, arg)
+		if err != nil {
+			return err
+		}
+		file := filepath.ToSlash(p)
+		anchors, err := c.Anchors(fsys, file)
+		if err != nil {
+			return err
+		}
+		for _, a := range anchors {
+			if *format == "json" {
+				out = append(out, jsonAnchor{
+					File:   file,
+					ID:     a.ID,
+					Level:  a.Level,
+					Text:   a.Text,
+					Line:   a.Line,
+					Column: a.Column,
+					Cell:   a.Cell,
+				})
+				continue
+			}
+			fmt.Printf("%s:%d: #%s", file, a.Line, a.ID)
+			if a.Text != "" {
+				fmt.Printf(" %q", a.Text)
+			}
+			fmt.Println()
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	return nil
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runLSP implements the “lsp” subcommand: the Language Server Protocol
+// server, talking over stdin and stdout.
+func runLSP(args []string) error {
+	fset := flag.NewFlagSet("mdlinks lsp", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks lsp [flags]\n\n"+
+			"Runs the Language Server Protocol server over stdio, for editors like VS Code or Neovim.\n"+
+			"It publishes diagnostics for broken links of open documents as they are edited,\n"+
+			"provides go-to-definition for link targets, and completion for anchors of link targets.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	fset.Parse(args)
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	root, err := filepath.Abs(opts.dir)
+	if err != nil {
+		return err
+	}
+	srv, err := newLSPServer(c, fsys, root, os.Stdout)
+	if err != nil {
+		return err
+	}
+	return srv.serve(bufio.NewReader(os.Stdin))
+}
+
+// newLSPServer returns the server checking documents of fsys, the
+// filesystem of the root directory, with c, and writing messages to out.
+func newLSPServer(c *mdlinks.Checker, fsys fs.FS, root string, out io.Writer) (*lspServer, error) {
+	overlay := &overlayFS{FS: fsys, files: make(map[string][]byte)}
+	session, err := c.NewSession(overlay)
+	if err != nil {
+		return nil, err
+	}
+	local := *c
+	local.CheckExternal = false
+	return &lspServer{
+		c:        c,
+		local:    &local,
+		session:  session,
+		fsys:     overlay,
+		external: make(map[string][]mdlinks.BrokenLink),
+		root:     root,
+		out:      out,
+	}, nil
+}
+
+// lspServer handles LSP requests for documents of the root directory.
+type lspServer struct {
+	c       *mdlinks.Checker
+	local   *mdlinks.Checker // c without external link checks, used while editing
+	session *mdlinks.Session // tells which documents are affected by changes
+	fsys    *overlayFS       // root directory with unsaved content of open documents
+	root    string           // absolute path of the directory checked
+	out     io.Writer
+
+	// external holds unreachable external links of open documents, found
+	// when they were last opened or saved, and reported along with local
+	// checks of documents linking to the edited ones
+	external map[string][]mdlinks.BrokenLink
+
+	shutdown bool // “shutdown” request was received
+}
+
+// lspMessage is a JSON-RPC request, notification, or response.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// serve reads messages from r until the “exit” notification.
+func (srv *lspServer) serve(r *bufio.Reader) error {
+	for {
+		body, err := readLSPMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !srv.shutdown {
+				os.Exit(1)
+			}
+			return nil
+		}
+		result, err := srv.handle(msg.Method, msg.Params)
+		if msg.ID == nil { // notification
+			var le *lspError
+			if err != nil && !errors.As(err, &le) { // unsupported notifications are ignored
+				log.Printf("%s: %v", msg.Method, err)
+			}
+			continue
+		}
+		resp := lspMessage{JSONRPC: "2.0", ID: msg.ID, Result: result}
+		var le *lspError
+		switch {
+		case errors.As(err, &le):
+			resp.Error = le
+		case err != nil:
+			resp.Error = &lspError{Code: -32603, Message: err.Error()}
+		case result == nil:
+			resp.Result = json.RawMessage("null")
+		}
+		if err := srv.send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *lspError) Error() string { return e.Message }
+
+// readLSPMessage reads a single message body, prefixed with the
+// Content-Length header, from r.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	size := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(k, "Content-Length") {
+			if size, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+			}
+		}
+	}
+	if size < 0 {
+		return nil, errors.New("message without the Content-Length header")
+	}
+	body := make([]byte, size)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+// send writes msg, prefixed with the Content-Length header.
+func (srv *lspServer) send(msg lspMessage) error {
+	msg.JSONRPC = "2.0"
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(srv.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // in UTF-16 code units
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+}
+
+// handle handles the request or notification method with params, returning
+// the result to respond with.
+func (srv *lspServer) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full content
+					"save":      true,
+				},
+				"definitionProvider": true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{"#"}},
+			},
+			"serverInfo": map[string]string{"name": "mdlinks"},
+		}, nil
+	case "shutdown":
+		srv.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave", "textDocument/didClose":
+		var p struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		name, ok := srv.path(p.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+		switch method {
+		case "textDocument/didOpen":
+			srv.fsys.files[name] = []byte(p.TextDocument.Text)
+		case "textDocument/didChange":
+			if n := len(p.ContentChanges); n != 0 {
+				srv.fsys.files[name] = []byte(p.ContentChanges[n-1].Text)
+			}
+		case "textDocument/didClose":
+			delete(srv.fsys.files, name)
+			delete(srv.external, name)
+			if err := srv.publish(p.TextDocument.URI, nil); err != nil {
+				return nil, err
+			}
+		}
+		// external links are only checked on open and save, not on
+		// each keystroke
+		return nil, srv.checkAffected(name, method != "textDocument/didChange")
+	case "textDocument/definition":
+		var p lspPositionParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return srv.definition(p)
+	case "textDocument/completion":
+		var p lspPositionParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return srv.completion(p)
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	}
+	return nil, &lspError{Code: -32601, Message: "method not supported: " + method}
+}
+
+// checkAffected checks the changed document name, if it's open, and other
+// open documents that link to it, publishing their diagnostics. External
+// links of the changed document are checked if external is set, otherwise
+// they are not reported until the next check with external set.
+func (srv *lspServer) checkAffected(name string, external bool) error {
+	affected, err := srv.session.Affected(name)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if _, ok := srv.fsys.files[name]; ok {
+		// may be a new document, not saved yet
+		names = append(names, name)
+	}
+	for _, p := range affected {
+		if _, ok := srv.fsys.files[p]; ok && p != name {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+	names, err = checkedFiles(srv.c, names)
+	if err != nil {
+		return err
+	}
+	for _, p := range names {
+		var links []mdlinks.BrokenLink
+		switch {
+		case p == name && external:
+			if links, err = srv.c.CheckFile(srv.fsys, p); err != nil {
+				return err
+			}
+			srv.external[p] = nil
+			for _, l := range links {
+				if l.Kind() == "unreachable-url" {
+					srv.external[p] = append(srv.external[p], l)
+				}
+			}
+		case p == name:
+			// positions of the stored links are stale now
+			delete(srv.external, p)
+			if links, err = srv.local.CheckFile(srv.fsys, p); err != nil {
+				return err
+			}
+		default:
+			if links, err = srv.local.CheckFile(srv.fsys, p); err != nil {
+				return err
+			}
+			links = append(links, srv.external[p]...)
+		}
+		if err := srv.publish(srv.uri(p), srv.diagnostics(p, links)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diagnostics returns LSP diagnostics of broken links of the open document
+// name.
+func (srv *lspServer) diagnostics(name string, links []mdlinks.BrokenLink) []map[string]any {
+	var diags []map[string]any
+	body := srv.fsys.files[name]
+	for _, l := range links {
+		if l.Link.Line == 0 || l.Link.Cell != 0 {
+			continue
+		}
+		sev := 1
+		if l.IsWarning() {
+			sev = 2
+		}
+		msg := l.Reason()
+		if l.Suggestion != "" {
+			msg += ", did you mean " + strconv.Quote(l.Suggestion) + "?"
+		}
+		diags = append(diags, map[string]any{
+			"range":    linkRange(body, l.Link),
+			"severity": sev,
+			"code":     l.Kind(),
+			"source":   "mdlinks",
+			"message":  msg,
+		})
+	}
+	return diags
+}
+
+// publish sends diagnostics of the document uri.
+func (srv *lspServer) publish(uri string, diags []map[string]any) error {
+	if diags == nil {
+		diags = []map[string]any{}
+	}
+	params, err := json.Marshal(map[string]any{"uri": uri, "diagnostics": diags})
+	if err != nil {
+		return err
+	}
+	return srv.send(lspMessage{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// definition returns the location of the target of the link under the
+// cursor: the anchor its fragment points to, or the start of the file.
+func (srv *lspServer) definition(p lspPositionParams) (any, error) {
+	name, ok := srv.path(p.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	body, err := fs.ReadFile(srv.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	fl, err := srv.c.DocumentLinks(srv.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range fl.Links {
+		if l.External || !l.Exists || l.Line == 0 || l.Cell != 0 {
+			continue
+		}
+		r := linkRange(body, l.LinkInfo)
+		if p.Position.Line != r.Start.Line || p.Position.Character < r.Start.Character || p.Position.Character > r.End.Character {
+			continue
+		}
+		if fi, err := fs.Stat(srv.fsys, l.Target); err != nil || fi.IsDir() {
+			return nil, nil
+		}
+		var pos lspPosition
+		if l.Fragment != "" {
+			if anchors, err := srv.c.Anchors(srv.fsys, l.Target); err == nil {
+				target, _ := fs.ReadFile(srv.fsys, l.Target)
+				for _, a := range anchors {
+					if a.ID == l.Fragment && a.Line != 0 && a.Cell == 0 {
+						pos = lspPosition{Line: a.Line - 1, Character: utf16Column(target, a.Offset)}
+						break
+					}
+				}
+			}
+		}
+		return map[string]any{"uri": srv.uri(l.Target), "range": lspRange{Start: pos, End: pos}}, nil
+	}
+	return nil, nil
+}
+
+// linkDestRe matches the start of the link destination being typed at the
+// end of the line text, like “[text](guide.md#ins”, “[ref]: guide.md#ins”,
+// or “href="guide.md#ins”; its group is the destination typed so far.
+var linkDestRe = regexp.MustCompile(`(?:\]\(<?|^\s*\[[^\]]+\]:\s*<?|(?:href|src)=["'])([^\s()<>"']*)$`)
+
+// completion returns anchors of the link target, when the cursor is in the
+// fragment of the link destination.
+func (srv *lspServer) completion(p lspPositionParams) (any, error) {
+	name, ok := srv.path(p.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	body, err := fs.ReadFile(srv.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(body, []byte{'\n'})
+	if p.Position.Line >= len(lines) {
+		return nil, nil
+	}
+	line := lines[p.Position.Line]
+	prefix := line[:byteColumn(line, p.Position.Character)]
+	m := linkDestRe.FindSubmatch(prefix)
+	if m == nil || !bytes.Contains(m[1], []byte{'#'}) {
+		return nil, nil
+	}
+	dest := string(m[1])
+	typed := dest[strings.IndexByte(dest, '#')+1:]
+	target, _ := mdlinks.ResolveLink(name, dest)
+	if target == "" || strings.HasPrefix(target, "../") {
+		return nil, nil
+	}
+	anchors, err := srv.c.Anchors(srv.fsys, target)
+	if err != nil {
+		return nil, nil // target doesn't exist yet
+	}
+	start := lspPosition{Line: p.Position.Line, Character: p.Position.Character - utf16Len([]byte(typed))}
+	items := []map[string]any{}
+	seen := make(map[string]bool)
+	for _, a := range anchors {
+		if seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+		item := map[string]any{
+			"label":    a.ID,
+			"kind":     18, // reference
+			"textEdit": map[string]any{"range": lspRange{Start: start, End: p.Position}, "newText": a.ID},
+		}
+		if a.Text != "" {
+			item["detail"] = strings.Repeat("#", a.Level) + " " + a.Text
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// path returns the path of the document uri relative to the root, and
+// false if it's outside of the root.
+func (srv *lspServer) path(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	rel, err := filepath.Rel(srv.root, filepath.FromSlash(u.Path))
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel, fs.ValidPath(rel) && rel != "."
+}
+
+// uri returns the URI of the document name relative to the root.
+func (srv *lspServer) uri(name string) string {
+	u := url.URL{Scheme: "file", Path: path.Join(filepath.ToSlash(srv.root), name)}
+	if !strings.HasPrefix(u.Path, "/") { // Windows paths, like C:/docs
+		u.Path = "/" + u.Path
+	}
+	return u.String()
+}
+
+// linkRange returns the range of the destination of l in body.
+func linkRange(body []byte, l mdlinks.LinkInfo) lspRange {
+	start := lspPosition{Line: l.Line - 1, Character: utf16Column(body, l.Offset)}
+	end := start
+	if l.Offset+len(l.Raw) <= len(body) && string(body[l.Offset:l.Offset+len(l.Raw)]) == l.Raw {
+		end.Character += utf16Len([]byte(l.Raw))
+	}
+	return lspRange{Start: start, End: end}
+}
+
+// utf16Column returns the column of the byte offset off of body, in UTF-16
+// code units, as LSP positions use.
+func utf16Column(body []byte, off int) int {
+	if off > len(body) {
+		off = len(body)
+	}
+	lineStart := bytes.LastIndexByte(body[:off], '\n') + 1
+	return utf16Len(body[lineStart:off])
+}
+
+// utf16Len returns the length of b in UTF-16 code units.
+func utf16Len(b []byte) int {
+	var n int
+	for len(b) != 0 {
+		r, size := utf8.DecodeRune(b)
+		n += len(utf16.Encode([]rune{r}))
+		b = b[size:]
+	}
+	return n
+}
+
+// byteColumn returns the byte offset in line of the column col, given in
+// UTF-16 code units.
+func byteColumn(line []byte, col int) int {
+	var off int
+	for off < len(line) && col > 0 {
+		r, size := utf8.DecodeRune(line[off:])
+		col -= len(utf16.Encode([]rune{r}))
+		off += size
+	}
+	return off
+}
+
+// overlayFS is the file system with the content of some files replaced,
+// like with unsaved content of documents open in the editor.
+type overlayFS struct {
+	fs.FS
+	files map[string][]byte
+}
+
+func (fsys *overlayFS) Open(name string) (fs.File, error) {
+	body, ok := fsys.files[name]
+	if !ok {
+		return fsys.FS.Open(name)
+	}
+	fi := &overlayInfo{name: path.Base(name), size: int64(len(body))}
+	if f, err := fsys.FS.Open(name); err == nil {
+		if orig, err := f.Stat(); err == nil {
+			fi.mode, fi.modTime = orig.Mode(), orig.ModTime()
+		}
+		f.Close()
+	}
+	return &overlayFile{Reader: bytes.NewReader(body), info: fi}, nil
+}
+
+// overlayFile is an open file of the overlayFS with replaced content.
+type overlayFile struct {
+	*bytes.Reader
+	info *overlayInfo
+}
+
+func (f *overlayFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *overlayFile) Close() error               { return nil }
+
+type overlayInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi *overlayInfo) Name() string       { return fi.name }
+func (fi *overlayInfo) Size() int64        { return fi.size }
+func (fi *overlayInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *overlayInfo) ModTime() time.Time { return fi.modTime }
+func (fi *overlayInfo) IsDir() bool        { return false }
+func (fi *overlayInfo) Sys() any           { return nil }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/artyom/mdlinks"
+)
+
+func TestLSPServer_checkAffected(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.md": {Data: []byte("[b](b.md#setup) [x](https://example.invalid/x)\n")},
+		"b.md": {Data: []byte("# B\n\n## Setup\n")},
+		"c.md": {Data: []byte("[y](https://example.invalid/y)\n")},
+	}
+	var requests int
+	c := &mdlinks.Checker{
+		Matcher:       func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		CheckExternal: true,
+		HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		})},
+	}
+	var out bytes.Buffer
+	srv, err := newLSPServer(c, fsys, t.TempDir(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// notify sends the notification, and returns published diagnostics
+	// codes by document, like “a.md:missing-anchor,unreachable-url”, along
+	// with the number of HTTP requests made
+	notify := func(method, name, text string) (string, int) {
+		t.Helper()
+		out.Reset()
+		requests = 0
+		doc := map[string]any{"uri": srv.uri(name), "text": text}
+		params, err := json.Marshal(map[string]any{"textDocument": doc, "contentChanges": []any{map[string]string{"text": text}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := srv.handle(method, params); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, msg := range strings.Split(out.String(), "Content-Length: ")[1:] {
+			_, body, _ := strings.Cut(msg, "\r\n\r\n")
+			var m struct {
+				Params struct {
+					URI         string
+					Diagnostics []struct{ Code string }
+				}
+			}
+			if err := json.Unmarshal([]byte(body), &m); err != nil {
+				t.Fatal(err)
+			}
+			var codes []string
+			for _, d := range m.Params.Diagnostics {
+				codes = append(codes, d.Code)
+			}
+			sort.Strings(codes)
+			name, _ := srv.path(m.Params.URI)
+			got = append(got, name+":"+strings.Join(codes, ","))
+		}
+		return strings.Join(got, " "), requests
+	}
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		notify("textDocument/didOpen", name, string(fsys[name].Data))
+	}
+	for _, tc := range []struct {
+		method, name, text string
+		want               string
+		requests           int
+	}{
+		{"textDocument/didChange", "b.md", "# B\n", "a.md:missing-anchor,unreachable-url b.md:", 0},
+		{"textDocument/didChange", "a.md", "[b](b.md#setup) [x](https://example.invalid/x)\n", "a.md:missing-anchor", 0},
+		{"textDocument/didSave", "a.md", "", "a.md:missing-anchor,unreachable-url", 1},
+		{"textDocument/didClose", "b.md", "", "b.md: a.md:unreachable-url", 0},
+	} {
+		got, requests := notify(tc.method, tc.name, tc.text)
+		if got != tc.want || requests != tc.requests {
+			t.Errorf("%s %s: got %q with %d requests, want %q with %d", tc.method, tc.name, got, requests, tc.want, tc.requests)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
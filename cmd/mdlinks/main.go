@@ -3,40 +3,434 @@ package main
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/artyom/mdlinks"
 )
 
 func main() {
 	log.SetFlags(0)
-	dir := "."
-	pat := "*.md"
-	flag.StringVar(&dir, "dir", dir, "`directory` to scan; it's considered to be a root for absolute links")
-	flag.StringVar(&pat, "pat", pat, "glob `pattern` to match markdown files")
-	flag.Parse()
-	err := mdlinks.CheckFS(os.DirFS(dir), pat)
-	var e *mdlinks.BrokenLinksError
-	if errors.As(err, &e) {
-		isGithub := os.Getenv("GITHUB_ACTIONS") == "true"
-		for _, l := range e.Links {
-			log.Println(l)
-			if isGithub {
-				// https://docs.github.com/en/actions/learn-github-actions/workflow-commands-for-github-actions#setting-an-error-message
-				// ::error file={name},line={line},endLine={endLine},title={title}::{message}
-				switch l.Link.LineStart {
-				case 0:
-					log.Printf("::error file=%s,title=%s::%s", l.File, l.Reason(), l)
-				default:
-					log.Printf("::error file=%s,line=%d,endLine=%d,title=%s::%s",
-						l.File, l.Link.LineStart, l.Link.LineEnd, l.Reason(), l)
+	cmd, args := runCheck, os.Args[1:]
+	if len(args) != 0 {
+		if c, ok := commands[args[0]]; ok {
+			cmd, args = c, args[1:]
+		}
+	}
+	err := cmd(args)
+	var code exitCode
+	if errors.As(err, &code) {
+		os.Exit(int(code))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// exitCode is returned by runCheck to exit with the code, see -exit-code.
+type exitCode int
+
+func (c exitCode) Error() string { return "exit code " + strconv.Itoa(int(c)) }
+
+// failOn values mapped to functions reporting whether the broken link fails
+// the check.
+var failOn = map[string]func(mdlinks.BrokenLink) bool{
+	"error":   func(l mdlinks.BrokenLink) bool { return !l.IsWarning() },
+	"warning": func(mdlinks.BrokenLink) bool { return true },
+	"none":    func(mdlinks.BrokenLink) bool { return false },
+}
+
+// runCheck implements the “check” subcommand, which is also the default one:
+// it checks links and reports the broken ones.
+func runCheck(args []string) error {
+	fset := flag.NewFlagSet("mdlinks check", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks [check] [flags] [file...]\n\n"+
+			"Checks links of documents, and reports the broken ones.\n\n"+
+			"Other commands, run “mdlinks command -h” for their flags:\n\n"+
+			"  list           lists links of documents\n"+
+			"  anchors        lists anchors of documents\n"+
+			"  backlinks      lists links to files\n"+
+			"  graph          writes the graph of links\n"+
+			"  fix            rewrites broken links with unambiguous fixes\n"+
+			"  mv             moves files, rewriting links to them\n"+
+			"  rename-anchor  rewrites links to an anchor\n"+
+			"  hook           installs the git pre-commit hook\n"+
+			"  serve          serves checks over HTTP\n"+
+			"  lsp            runs the Language Server Protocol server\n\n"+
+			"Flags:")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	format := "text"
+	var baseline string
+	var showContext bool
+	color := "auto"
+	var repo, as string
+	var stdin, changed, staged, watchFiles, version bool
+	failLevel, code := "error", 127
+	var maxErrors int
+	var failFast bool
+	base := "HEAD"
+	fset.StringVar(&format, "format", format, "output `format`: "+formatNames())
+	fset.StringVar(&baseline, "baseline", baseline, "baseline `file` with known broken links to ignore;\n"+
+		"if it does not exist, it is created with all currently broken links")
+	fset.BoolVar(&showContext, "show-context", showContext, "with the text format, show source lines of broken links,\n"+
+		"marking their destinations")
+	fset.StringVar(&color, "color", color, "color the text format output: auto, always, or never;\n"+
+		"auto enables colors if stderr is a terminal and NO_COLOR is not set")
+	fset.StringVar(&repo, "repo", repo, "`URL` of the git repository, like https://github.com/org/repo@branch, to check\n"+
+		"its shallow clone instead of a local directory; -dir is relative to the clone")
+	fset.BoolVar(&stdin, "stdin", stdin, "check the single document read from stdin instead, as if it was stored under -as")
+	fset.StringVar(&as, "as", as, "with -stdin, the `path` of the document inside -dir, like docs/guide.md,\n"+
+		"its links are resolved against")
+	fset.BoolVar(&changed, "changed", changed, "only check documents changed since the merge base with the -base git ref,\n"+
+		"including uncommitted ones, and documents linking to changed or deleted files")
+	fset.StringVar(&base, "base", base, "with -changed, the git `ref` to compare with, like origin/main")
+	fset.BoolVar(&staged, "staged", staged, "only check documents staged in the git index, and documents linking to staged files,\n"+
+		"reading their staged content instead of the working tree; see “mdlinks hook install”")
+	fset.BoolVar(&watchFiles, "watch", watchFiles, "keep running, re-checking documents affected by changed files after each save,\n"+
+		"and reporting results of each check")
+	fset.StringVar(&failLevel, "fail-on", failLevel, "fail with -exit-code if broken links of this `severity` are found:\n"+
+		"error, warning (errors or warnings), or none, to only report them")
+	fset.IntVar(&code, "exit-code", code, "exit `code` to use if the check fails, from 1 to 255;\n"+
+		"other errors, like invalid flags or unreadable files, exit with code 1")
+	fset.IntVar(&maxErrors, "max-errors", maxErrors, "stop checking after this `number` of broken links, including warnings,\n"+
+		"and only report them; 0 means no limit")
+	fset.BoolVar(&failFast, "fail-fast", failFast, "stop checking at the first broken link, same as -max-errors 1")
+	fset.BoolVar(&version, "version", version, "print the version, the VCS revision, and versions of Go and goldmark, and exit")
+	fset.Parse(args)
+	if version {
+		writeVersion(os.Stdout)
+		return nil
+	}
+	if stdin && !fs.ValidPath(as) || stdin && as == "." {
+		return errors.New("-stdin needs a valid -as path inside -dir, like docs/guide.md")
+	}
+	fails, ok := failOn[failLevel]
+	if !ok {
+		return fmt.Errorf("unsupported -fail-on value %q, supported values are: error, none, warning", failLevel)
+	}
+	if code < 1 || code > 255 {
+		return fmt.Errorf("-exit-code must be from 1 to 255, got %d", code)
+	}
+	if failFast {
+		maxErrors = 1
+	}
+	report, ok := reporters[format]
+	if !ok {
+		return fmt.Errorf("unsupported -format value %q, supported values are: %s", format, formatNames())
+	}
+	useColor, err := colorOutput(color)
+	if err != nil {
+		return err
+	}
+	if format == "text" && useColor {
+		report = reportColorText
+	}
+	dirs := append([]string{opts.dir}, opts.moreDirs...)
+	if (changed || staged) && (stdin || fset.NArg() != 0) || changed && staged {
+		return errors.New("-changed and -staged can't be used together, or with -stdin or file arguments")
+	}
+	if len(dirs) > 1 && (stdin || fset.NArg() != 0) {
+		return errors.New("-stdin and file arguments can't be used with multiple -dir flags")
+	}
+	if watchFiles && (stdin || fset.NArg() != 0 || changed || staged || repo != "" || len(dirs) > 1 || len(opts.packages) != 0) {
+		return errors.New("-watch can't be used with -stdin, -changed, -staged, -repo, -package, file arguments, or multiple -dir flags")
+	}
+	var clone string
+	if repo != "" {
+		if clone, err = cloneRepo(repo); err != nil {
+			return err
+		}
+		defer os.RemoveAll(clone)
+	}
+	res := &result{}
+	if showContext {
+		res.source = newSources(os.DirFS("."))
+	}
+	remaining := maxErrors // -max-errors budget left for the following trees
+	var stopped bool       // whether the check stopped at -max-errors
+	for _, dir := range dirs {
+		o := *opts
+		o.dir, o.moreDirs = dir, nil
+		reportDir := dir
+		if clone != "" {
+			// report paths relative to the repository root
+			reportDir = path.Clean(filepath.ToSlash(dir))
+			o.dir = filepath.Join(clone, filepath.FromSlash(reportDir))
+		}
+		var files []string
+		fsys, c, closer, err := o.checker(&files)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		if baseline == "" {
+			// with the baseline, the limit applies to links not in it
+			c.MaxViolations = remaining
+		}
+		var links []mdlinks.BrokenLink
+		var body []byte // document read from stdin
+		switch {
+		case stdin:
+			if body, err = io.ReadAll(os.Stdin); err == nil {
+				links, err = c.CheckBytes(fsys, as, body)
+			}
+			files = []string{as}
+		case fset.NArg() != 0:
+			if files, err = listedFiles(o.dir, c, fset.Args()); err == nil {
+				links, err = c.CheckFiles(fsys, files...)
+			}
+		case changed:
+			files, links, err = checkChanged(o.dir, base, c, fsys)
+		case staged:
+			fsys, files, links, err = checkStaged(o.dir, c)
+		case watchFiles:
+			err = watch(o.dir, fsys, c, func(files []string, links []mdlinks.BrokenLink) error {
+				res := &result{dir: reportDir, files: files, links: links}
+				if showContext {
+					res.source = newSources(fsys)
+				}
+				if baseline != "" {
+					if res.links, err = applyBaseline(baseline, links); err != nil {
+						return err
+					}
+				}
+				return report(os.Stdout, res)
+			})
+		default:
+			links, err = brokenLinks(fsys, c)
+		}
+		if err != nil {
+			return err
+		}
+		if baseline == "" && c.LimitReached(links) {
+			stopped = true
+		}
+		remaining -= len(links)
+		if len(dirs) == 1 {
+			res.dir, res.files, res.links = reportDir, files, links
+			if showContext {
+				res.source = newSources(fsys)
+				if stdin {
+					res.source.files[as] = body
+				}
+			}
+			break
+		}
+		// paths of files of multiple trees are relative to the current
+		// directory, so that reports stay unambiguous
+		prefix := filepath.ToSlash(reportDir)
+		for _, p := range files {
+			res.files = append(res.files, path.Join(prefix, p))
+		}
+		for _, l := range links {
+			name := path.Join(prefix, l.File)
+			if res.source != nil {
+				if _, ok := res.source.files[name]; !ok {
+					res.source.files[name], _ = fs.ReadFile(fsys, l.File)
 				}
 			}
+			l.File = name
+			res.links = append(res.links, l)
 		}
-		os.Exit(127)
+		if stopped {
+			break
+		}
+	}
+	links := res.links
+	var truncated bool // whether links not in the baseline were cut to -max-errors
+	if baseline != "" {
+		if links, err = applyBaseline(baseline, links); err != nil {
+			return err
+		}
+		if truncated = maxErrors > 0 && len(links) > maxErrors; truncated {
+			links = links[:maxErrors]
+		}
+		res.links = links
+	}
+	if err := report(os.Stdout, res); err != nil {
+		return err
+	}
+	switch {
+	case stopped:
+		log.Printf("stopped checking after reaching -max-errors %d", maxErrors)
+	case truncated:
+		log.Printf("only reported the first %d broken links not in the baseline, see -max-errors", maxErrors)
 	}
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		githubAnnotations(res.dir, links)
+	}
+	for _, l := range links {
+		if fails(l) {
+			return exitCode(code)
+		}
+	}
+	return nil
+}
+
+// commands maps names of subcommands to their implementations, which take
+// arguments following the subcommand name.
+var commands = map[string]func(args []string) error{
+	"fix":       runFix,
+	"mv":        runMv,
+	"graph":     runGraph,
+	"backlinks": runBacklinks,
+	"check":     runCheck,
+	"list":      runList,
+	"anchors":   runAnchors,
+	"hook":      runHook,
+	"serve":     runServe,
+	"lsp":       runLSP,
+
+	"rename-anchor": runRenameAnchor,
+}
+
+// listedFiles returns paths of files, given as command-line arguments,
+// relative to dir, skipping the ones not matched or excluded by c, so that
+// hooks can pass all changed files.
+func listedFiles(dir string, c *mdlinks.Checker, files []string) ([]string, error) {
+	var paths []string
+	for _, arg := range files {
+		p, err := dirRelative(dir, arg)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.ToSlash(p))
+	}
+	return checkedFiles(c, paths)
+}
+
+// checkedFiles returns paths of fsys, skipping the ones not matched or
+// excluded by c.
+func checkedFiles(c *mdlinks.Checker, paths []string) ([]string, error) {
+	var out []string
+	for _, p := range paths {
+		skip, err := excludedFile(c, p)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		ok, err := c.Matcher(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// excludedFile reports whether c.Exclude skips the file p or any of its
+// parent directories.
+func excludedFile(c *mdlinks.Checker, p string) (bool, error) {
+	if c.Exclude == nil {
+		return false, nil
+	}
+	for d := path.Dir(p); d != "."; d = path.Dir(d) {
+		if skip, err := c.Exclude(d, true); err != nil || skip {
+			return skip, err
+		}
+	}
+	return c.Exclude(p, false)
+}
+
+func readRedirects(name string) (map[string]string, error) {
+	f, err := os.Open(name)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	defer f.Close()
+	return mdlinks.ParseRedirects(f)
+}
+
+var wikiModes = map[string]mdlinks.WikiLinkMode{
+	"":         mdlinks.WikiLinksDisabled,
+	"same-dir": mdlinks.WikiLinksSameDir,
+	"shortest": mdlinks.WikiLinksShortestPath,
+}
+
+var linkStyles = map[string]mdlinks.LinkStyle{
+	"":         mdlinks.LinkStyleAny,
+	"relative": mdlinks.LinkStyleRelative,
+	"absolute": mdlinks.LinkStyleAbsolute,
+}
+
+var queryPolicies = map[string]mdlinks.QueryPolicy{
+	"ignore": mdlinks.QueryIgnore,
+	"warn":   mdlinks.QueryWarn,
+	"error":  mdlinks.QueryError,
+}
+
+var dialects = map[string]mdlinks.Dialect{
+	"default":    mdlinks.DialectDefault,
+	"commonmark": mdlinks.DialectCommonMark,
+	"gfm":        mdlinks.DialectGFM,
+	"goldmark":   mdlinks.DialectGoldmark,
+}
+
+var slugAlgorithms = map[string]mdlinks.SlugAlgorithm{
+	"default":    mdlinks.SlugDefault,
+	"github":     mdlinks.SlugGitHub,
+	"gitlab":     mdlinks.SlugGitLab,
+	"azure":      mdlinks.SlugAzureDevOps,
+	"kramdown":   mdlinks.SlugKramdown,
+	"vscode":     mdlinks.SlugVSCode,
+	"pandoc":     mdlinks.SlugPandoc,
+	"pandoc-gfm": mdlinks.SlugPandocGFM,
+}
+
+func slugNames() string {
+	names := make([]string, 0, len(slugAlgorithms))
+	for k := range slugAlgorithms {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ignoreFile is a name of the file in the scanned directory root listing
+// gitignore-style patterns of paths to skip
+const ignoreFile = ".mdlinksignore"
+
+// excludeMatcher returns a function for mdlinks.Checker.Exclude built from
+// patterns found in the ignoreFile at the root of fsys and extra patterns.
+// It returns nil if there are no patterns.
+func excludeMatcher(fsys fs.FS, extra []string) (func(string, bool) (bool, error), error) {
+	var patterns []string
+	b, err := fs.ReadFile(fsys, ignoreFile)
+	switch {
+	case err == nil:
+		patterns = strings.Split(string(b), "\n")
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	patterns = append(patterns, extra...)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return mdlinks.ExcludePatterns(patterns)
+}
+
+// stringsFlag is a flag.Value collecting values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ", ") }
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunCheck_maxErrorsMultipleDirs(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		body := "[1](gone1.md) [2](gone2.md) [3](gone3.md)\n"
+		if err := os.WriteFile(filepath.Join(root, dir, "doc.md"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "b", "other.md"), []byte("[ok](doc.md)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		maxErrors int
+		want      string // checked files with numbers of their broken links
+		stopped   bool
+	}{
+		{maxErrors: 4, want: "a/doc.md:3 b/doc.md:1", stopped: true},
+		{maxErrors: 2, want: "a/doc.md:2", stopped: true},
+		{maxErrors: 7, want: "a/doc.md:3 b/doc.md:3 b/other.md:0"},
+	} {
+		out, logged := captureOutput(t, func() {
+			err := runCheck([]string{"-format", "tap", "-max-errors", strconv.Itoa(tc.maxErrors),
+				"-dir", filepath.Join(root, "a"), "-dir", filepath.Join(root, "b")})
+			if _, ok := err.(exitCode); !ok {
+				t.Fatalf("-max-errors %d: got error %v, want exitCode", tc.maxErrors, err)
+			}
+		})
+		var got []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if _, file, ok := strings.Cut(line, " - "); ok {
+				file = strings.TrimPrefix(file, filepath.ToSlash(root)+"/")
+				got = append(got, file+":0")
+			} else if strings.HasPrefix(line, "# ") && len(got) != 0 {
+				file, n, _ := strings.Cut(got[len(got)-1], ":")
+				i, _ := strconv.Atoi(n)
+				got[len(got)-1] = file + ":" + strconv.Itoa(i+1)
+			}
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("-max-errors %d: got %q, want %q", tc.maxErrors, strings.Join(got, " "), tc.want)
+		}
+		if stopped := strings.Contains(logged, "stopped checking"); stopped != tc.stopped {
+			t.Errorf("-max-errors %d: got log %q, want stopped %v", tc.maxErrors, logged, tc.stopped)
+		}
+	}
+}
+
+// captureOutput calls fn, returning what it writes to os.Stdout and logs.
+func captureOutput(t *testing.T, fn func()) (stdout []byte, logged string) {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var logBuf bytes.Buffer
+	savedStdout, savedLog := os.Stdout, log.Writer()
+	os.Stdout = f
+	log.SetOutput(&logBuf)
+	defer func() {
+		os.Stdout = savedStdout
+		log.SetOutput(savedLog)
+	}()
+	fn()
+	if stdout, err = os.ReadFile(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return stdout, logBuf.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runMv implements the “mv” subcommand: it moves a file or directory and
+// rewrites links pointing to it, and relative links of moved documents.
+func runMv(args []string) error {
+	fset := flag.NewFlagSet("mdlinks mv", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks mv [flags] old new\n\n"+
+			"Moves a file or directory inside -dir, and rewrites links to keep them working.\n"+
+			"If new is an existing directory, old is moved inside it.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	dryRun := fset.Bool("n", false, "only print link changes, don't modify or move files")
+	fset.Parse(args)
+	if fset.NArg() != 2 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	from, err := dirRelative(opts.dir, fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := dirRelative(opts.dir, fset.Arg(1))
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(filepath.Join(opts.dir, to)); err == nil && fi.IsDir() {
+		to = filepath.Join(to, filepath.Base(from))
+	}
+	if _, err := os.Lstat(filepath.Join(opts.dir, to)); err == nil {
+		return fmt.Errorf("%s already exists", filepath.Join(opts.dir, to))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	fsys, c, closer, err := opts.checker(nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	f := &mdlinks.Fixer{WriteFile: opts.writeFile(*dryRun)}
+	fixes, err := f.Move(c, fsys, filepath.ToSlash(from), filepath.ToSlash(to))
+	printFixes(fixes)
+	if err != nil || *dryRun {
+		return err
+	}
+	dst := filepath.Join(opts.dir, to)
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(opts.dir, from), dst)
+}
+
+// dirRelative converts path p, relative to the current directory, to the
+// cleaned path relative to the directory dir, which must contain it.
+func dirRelative(dir, p string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of %s", p, dir)
+	}
+	return rel, nil
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/artyom/mdlinks"
+)
+
+// options are the flags configuring mdlinks.Checker, shared by subcommands
+// that check the tree.
+type options struct {
+	dir, pat, outerDir string
+	moreDirs           []string // values of -dir following the first one, see dirFlag
+
+	wiki, mkdocs, mdbook, redirects, slug, dialect, repoURL, siteURL, query, linkStyle string
+
+	external, gitignore, hugo, jekyll, mkdocsUnlisted, mdbookUnlisted bool
+	docusaurus, reportRedirects, lintSpaces, nfc, checkCase           bool
+	unusedRefs, placeholders, lintPaths, notebooks, asciidoc, rst     bool
+	goDoc, mailto                                                     bool
+
+	ignoreLinks, excludes, fmKeys, dirIndex, inferExts, extMap, extract stringsFlag
+	mounts, packages                                                    stringsFlag
+
+	timeout time.Duration
+	jobs    int
+}
+
+func newOptions() *options {
+	return &options{dir: ".", pat: "*.md", slug: "default", dialect: "default", query: "ignore", timeout: 10 * time.Second, jobs: runtime.NumCPU()}
+}
+
+// register defines flags for opts on fset.
+func (opts *options) register(fset *flag.FlagSet) {
+	fset.Var(&dirFlag{opts: opts}, "dir", "`directory` to scan; it's considered to be a root for absolute links;\n"+
+		"can also be a .zip, .tar, .tar.gz, or .tgz archive; when checking links,\n"+
+		"can be used multiple times to check separate trees in one run")
+	fset.StringVar(&opts.outerDir, "outer-dir", opts.outerDir, "`directory` containing -dir, like the repository root; links climbing\n"+
+		"above -dir, like ../../CONTRIBUTING.md, are checked against it")
+	fset.Var(&opts.mounts, "mount", "`point=directory` pair, like api=../build/api, so that links into the point\n"+
+		"directory of -dir, like /api/index.html, are resolved against another directory;\n"+
+		"can be used multiple times")
+	fset.Var(&opts.packages, "package", "`path=directory` pair, like services/a=../a, so that packages of a monorepo,\n"+
+		"kept in separate directories, are checked as a single tree with packages at\n"+
+		"their paths, instead of -dir; can be used multiple times")
+	fset.StringVar(&opts.pat, "pat", opts.pat, "comma-separated glob `patterns` to match markdown files, like 'docs/**/*.md,*.markdown';\n"+
+		"patterns without a slash match file names at any depth, ** matches any number of directories")
+	fset.IntVar(&opts.jobs, "j", opts.jobs, "`number` of files to check concurrently")
+	fset.BoolVar(&opts.notebooks, "notebooks", opts.notebooks, "also check markdown cells of Jupyter notebooks (*.ipynb files)")
+	fset.BoolVar(&opts.asciidoc, "asciidoc", opts.asciidoc, "also check cross references and links of AsciiDoc documents (*.adoc files)")
+	fset.BoolVar(&opts.rst, "rst", opts.rst, "also check roles, links, and section targets of reStructuredText documents (*.rst files)")
+	fset.BoolVar(&opts.goDoc, "go-doc", opts.goDoc, "also check links and [Name] doc links in doc comments of Go source files (*.go files)")
+	fset.StringVar(&opts.repoURL, "repo-url", opts.repoURL, "`URL` of the repository, like https://github.com/org/repo; absolute links\n"+
+		"to its files, like https://github.com/org/repo/blob/main/x.md, are checked as local links")
+	fset.StringVar(&opts.siteURL, "site-url", opts.siteURL, "`URL` of the site published from -dir, like https://docs.example.com/v2/;\n"+
+		"absolute links to its pages are checked as local links, with the URL path stripped")
+	fset.BoolVar(&opts.external, "external", opts.external, "also check that absolute http(s) links are reachable")
+	fset.DurationVar(&opts.timeout, "timeout", opts.timeout, "timeout for a single external link check")
+	fset.Var(&opts.ignoreLinks, "ignore-link", "regular `expression` matching links that should not be checked;\n"+
+		"can be used multiple times")
+	fset.Var(&opts.excludes, "exclude", "gitignore-style `pattern` of paths to skip, in addition to the ones\n"+
+		"listed in the "+ignoreFile+" file; can be used multiple times")
+	fset.BoolVar(&opts.gitignore, "respect-gitignore", opts.gitignore, "skip files and directories ignored by .gitignore files")
+	fset.StringVar(&opts.wiki, "wiki", opts.wiki, "check wiki-style [[links]], resolving them with the given `mode`:\n"+
+		"\"same-dir\" or \"shortest\" (find page anywhere, like Obsidian does)")
+	fset.BoolVar(&opts.hugo, "hugo", opts.hugo, "check Hugo ref and relref shortcodes; -dir should point to the Hugo content directory")
+	fset.BoolVar(&opts.jekyll, "jekyll", opts.jekyll, "check Jekyll link and post_url tags; -dir should point to the Jekyll site source directory")
+	fset.StringVar(&opts.mkdocs, "mkdocs", opts.mkdocs, "path to the MkDocs configuration `file` inside -dir to validate its nav entries")
+	fset.BoolVar(&opts.mkdocsUnlisted, "mkdocs-unlisted", opts.mkdocsUnlisted, "with -mkdocs, also report documents not listed in the nav")
+	fset.StringVar(&opts.mdbook, "mdbook", opts.mdbook, "path to the mdBook summary `file` inside -dir (e.g. src/SUMMARY.md) to validate its chapters")
+	fset.BoolVar(&opts.mdbookUnlisted, "mdbook-unlisted", opts.mdbookUnlisted, "with -mdbook, also report documents not listed in the summary")
+	fset.BoolVar(&opts.docusaurus, "docusaurus", opts.docusaurus, "resolve extensionless and slug-based links the way Docusaurus does")
+	fset.Var(&opts.fmKeys, "front-matter-link", "front matter `key` whose values are checked as local links, e.g. \"image\";\n"+
+		"can be used multiple times")
+	fset.StringVar(&opts.redirects, "redirects", opts.redirects, "`file` with redirect rules in the Netlify _redirects format;\n"+
+		"links to redirected locations are considered valid")
+	fset.BoolVar(&opts.reportRedirects, "report-redirects", opts.reportRedirects, "with -redirects, report links to redirected locations as warnings")
+	fset.Var(&opts.dirIndex, "dir-index", "`name` of the document representing its directory, like README.md;\n"+
+		"fragments of links to directories are checked against it; can be used multiple times")
+	fset.Var(&opts.inferExts, "infer-ext", "`extension` tried for links to non-existing files, like .md,\n"+
+		"so that ./page link is valid if ./page.md exists; can be used multiple times")
+	fset.Var(&opts.extMap, "ext-map", "`published=source` extension mapping, like .html=.md, so that a link to\n"+
+		"non-existing guide.html is checked against guide.md; can be used multiple times")
+	fset.Var(&opts.extract, "extract", "`.ext=regexp` pair, so that files with the .ext extension are also checked,\n"+
+		"with links found by regexp, like '.yaml=(?m)^\\s*- (\\S+\\.md)$': the first group of\n"+
+		"each match is checked as a link; can be used multiple times")
+	fset.StringVar(&opts.slug, "slug", opts.slug, "`algorithm` of generating header anchors: "+slugNames())
+	fset.StringVar(&opts.dialect, "dialect", opts.dialect, "markdown `flavor` documents are written in: default (GitHub Flavored Markdown\n"+
+		"with explicit header ids), commonmark, gfm, or goldmark (with definition lists)")
+	fset.StringVar(&opts.query, "query", opts.query, "`policy` for local links with query strings, like page.md?x=1: ignore\n"+
+		"(check them without the query), warn (report as warnings), or error (report as broken)")
+	fset.BoolVar(&opts.lintSpaces, "lint-spaces", opts.lintSpaces, "report links with unencoded spaces in their targets as warnings")
+	fset.BoolVar(&opts.nfc, "nfc", opts.nfc, "compare link paths and file names in Unicode normalization form C,\n"+
+		"so that links match files with differently normalized names, as on macOS")
+	fset.BoolVar(&opts.checkCase, "check-case", opts.checkCase, "report links that don't match the case of file names exactly")
+	fset.BoolVar(&opts.lintPaths, "lint-paths", opts.lintPaths, "report links to the document itself and links with redundant path segments,\n"+
+		"like ./a/../b.md, as warnings")
+	fset.StringVar(&opts.linkStyle, "link-style", opts.linkStyle, "report links to local files not of the given `style` as warnings:\n"+
+		"relative (to the linking document) or absolute (to -dir, like /docs/guide.md)")
+	fset.BoolVar(&opts.placeholders, "placeholders", opts.placeholders, "report links with empty destinations and placeholders, like # or TODO")
+	fset.BoolVar(&opts.mailto, "mailto", opts.mailto, "report mailto: links with missing, invalid, or duplicate email addresses as warnings")
+	fset.BoolVar(&opts.unusedRefs, "unused-refs", opts.unusedRefs, "report link reference definitions that are never used as warnings")
+}
+
+// dirFlag is the flag.Value of -dir: the first value replaces the default
+// directory, and the following ones are collected into opts.moreDirs.
+type dirFlag struct {
+	opts *options
+	set  bool
+}
+
+func (f *dirFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+	return f.opts.dir
+}
+
+func (f *dirFlag) Set(s string) error {
+	if f.set {
+		f.opts.moreDirs = append(f.opts.moreDirs, s)
+		return nil
+	}
+	f.opts.dir, f.set = s, true
+	return nil
+}
+
+// checker validates opts and returns the filesystem to scan with a Checker
+// configured for it, and the closer releasing the filesystem once it's no
+// longer used. If files is not nil, paths of processed files are appended to
+// it.
+func (opts *options) checker(files *[]string) (_ fs.FS, _ *mdlinks.Checker, _ io.Closer, err error) {
+	if len(opts.moreDirs) != 0 {
+		return nil, nil, nil, errors.New("-dir can only be used multiple times when checking links")
+	}
+	match, err := mdlinks.MatchPatterns(strings.Split(opts.pat, ","))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	wikiMode, ok := wikiModes[opts.wiki]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported -wiki value %q", opts.wiki)
+	}
+	slugAlgo, ok := slugAlgorithms[opts.slug]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported -slug value %q, supported values are: %s", opts.slug, slugNames())
+	}
+	dialect, ok := dialects[opts.dialect]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported -dialect value %q", opts.dialect)
+	}
+	queryPolicy, ok := queryPolicies[opts.query]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported -query value %q", opts.query)
+	}
+	linkStyle, ok := linkStyles[opts.linkStyle]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported -link-style value %q", opts.linkStyle)
+	}
+	fsys, closer, err := opts.fsys()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			closer.Close()
+		}
+	}()
+	var outerFS fs.FS
+	var outerDir string
+	if opts.outerDir != "" {
+		if len(opts.packages) != 0 {
+			return nil, nil, nil, errors.New("-outer-dir can't be used with -package")
+		}
+		outer, err := filepath.Abs(opts.outerDir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		dir, err := filepath.Abs(opts.dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rel, err := filepath.Rel(outer, dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if rel = filepath.ToSlash(rel); rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, nil, nil, fmt.Errorf("-outer-dir %q doesn't contain -dir %q", opts.outerDir, opts.dir)
+		}
+		outerFS, outerDir = os.DirFS(outer), rel
+	}
+	exclude, err := excludeMatcher(fsys, opts.excludes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	extensionMap := make(map[string]string)
+	for _, s := range opts.extMap {
+		from, to, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(from, ".") || !strings.HasPrefix(to, ".") {
+			return nil, nil, nil, fmt.Errorf("invalid -ext-map value %q, want .ext=.ext", s)
+		}
+		extensionMap[from] = to
+	}
+	extractors := make(map[string]mdlinks.LinkExtractor)
+	for _, s := range opts.extract {
+		ext, expr, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(ext, ".") {
+			return nil, nil, nil, fmt.Errorf("invalid -extract value %q, want .ext=regexp", s)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid -extract value %q: %w", s, err)
+		}
+		extractors[strings.ToLower(ext)] = mdlinks.RegexpExtractor(re)
+	}
+	mounts := make(map[string]fs.FS)
+	for _, s := range opts.mounts {
+		point, dir, ok := strings.Cut(s, "=")
+		if point = strings.Trim(point, "/"); !ok || !fs.ValidPath(point) || point == "." || dir == "" {
+			return nil, nil, nil, fmt.Errorf("invalid -mount value %q, want point=directory", s)
+		}
+		mounts[point] = os.DirFS(dir)
+	}
+	var redirectRules map[string]string
+	if opts.redirects != "" {
+		if redirectRules, err = readRedirects(opts.redirects); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	matcher := func(s string) (bool, error) {
+		switch ext := strings.ToLower(path.Ext(s)); {
+		case opts.notebooks && ext == ".ipynb", opts.asciidoc && ext == ".adoc", opts.rst && ext == ".rst",
+			opts.goDoc && ext == ".go", extractors[ext] != nil:
+			return true, nil
+		}
+		return match(s)
+	}
+	c := &mdlinks.Checker{
+		Exclude:          exclude,
+		RespectGitignore: opts.gitignore,
+		Matcher:          matcher,
+		CheckExternal:    opts.external,
+		HTTPClient:       &http.Client{Timeout: opts.timeout},
+		IgnoreLinks:      opts.ignoreLinks,
+		WikiLinks:        wikiMode,
+		HugoShortcodes:   opts.hugo,
+		JekyllTags:       opts.jekyll,
+		MkDocsConfig:     opts.mkdocs,
+		MkDocsUnlisted:   opts.mkdocsUnlisted,
+		MdBookSummary:    opts.mdbook,
+		MdBookUnlisted:   opts.mdbookUnlisted,
+		DocusaurusLinks:  opts.docusaurus,
+		FrontMatterLinks: opts.fmKeys,
+		Redirects:        redirectRules,
+		ReportRedirects:  opts.reportRedirects,
+		DirectoryIndex:   opts.dirIndex,
+		InferExtensions:  opts.inferExts,
+		ExtensionMap:     extensionMap,
+		FileExtractors:   extractors,
+		LintSpaces:       opts.lintSpaces,
+		NormalizeUnicode: opts.nfc,
+		CheckCase:        opts.checkCase,
+		UnusedReferences: opts.unusedRefs,
+		PlaceholderLinks: opts.placeholders,
+		LintPaths:        opts.lintPaths,
+		LinkStyle:        linkStyle,
+		RepoURL:          opts.repoURL,
+		SiteURL:          opts.siteURL,
+		QueryStrings:     queryPolicy,
+		CheckMailto:      opts.mailto,
+		OuterFS:          outerFS,
+		OuterDir:         outerDir,
+		Mounts:           mounts,
+		Dialect:          dialect,
+		Concurrency:      opts.jobs,
+	}
+	if slugAlgo != mdlinks.SlugDefault {
+		c.Slugger = func(text string) string { return mdlinks.Slugify(text, slugAlgo) }
+	}
+	if files != nil {
+		c.OnFile = func(p string) { *files = append(*files, p) }
+	}
+	return fsys, c, closer, nil
+}
+
+// fsys returns the filesystem to scan: either -dir, or the archive it names,
+// or the monorepo of -package directories, and the closer releasing it.
+func (opts *options) fsys() (fs.FS, io.Closer, error) {
+	if len(opts.packages) == 0 {
+		if fi, err := os.Stat(opts.dir); err == nil && !fi.IsDir() {
+			return archiveFS(opts.dir)
+		}
+		return os.DirFS(opts.dir), nopCloser{}, nil
+	}
+	for _, s := range opts.packages {
+		if _, dir, ok := strings.Cut(s, "="); !ok || dir == "" {
+			return nil, nil, fmt.Errorf("invalid -package value %q, want path=directory", s)
+		}
+	}
+	packages := make(map[string]fs.FS)
+	for p, dir := range opts.packageDirs() {
+		packages[p] = os.DirFS(dir)
+	}
+	fsys, err := mdlinks.MonorepoFS(packages)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fsys, nopCloser{}, nil
+}
+
+// archiveFS returns the filesystem of the zip or tar archive name, which may
+// be compressed with gzip, and the closer releasing it. Zip archives are read
+// from the open file as needed; tar ones are read into memory at once, and
+// their files are closed before archiveFS returns.
+func archiveFS(name string) (fs.FS, io.Closer, error) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr, nil
+	}
+	if !strings.HasSuffix(lower, ".tar") && !strings.HasSuffix(lower, ".tar.gz") && !strings.HasSuffix(lower, ".tgz") {
+		return nil, nil, fmt.Errorf("%s is not a directory or a .zip, .tar, .tar.gz, or .tgz archive", name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if !strings.HasSuffix(lower, ".tar") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	fsys, err := mdlinks.TarFS(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return fsys, nopCloser{}, nil
+}
+
+// nopCloser is the io.Closer of filesystems that need no closing.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// packageDirs returns directories of -package flags keyed by their paths.
+func (opts *options) packageDirs() map[string]string {
+	dirs := make(map[string]string)
+	for _, s := range opts.packages {
+		p, dir, _ := strings.Cut(s, "=")
+		dirs[strings.Trim(p, "/")] = dir
+	}
+	return dirs
+}
+
+// localPath returns the path of the file name of the scanned filesystem on
+// disk.
+func (opts *options) localPath(name string) string {
+	for p, dir := range opts.packageDirs() {
+		if rest := strings.TrimPrefix(name, p+"/"); rest != name {
+			return filepath.Join(dir, filepath.FromSlash(rest))
+		}
+	}
+	return filepath.Join(opts.dir, filepath.FromSlash(name))
+}
+
+// brokenLinks runs c over fsys and returns the broken links found.
+func brokenLinks(fsys fs.FS, c *mdlinks.Checker) ([]mdlinks.BrokenLink, error) {
+	err := c.CheckFS(fsys)
+	var e *mdlinks.BrokenLinksError
+	if err != nil && !errors.As(err, &e) {
+		return nil, err
+	}
+	if e != nil {
+		return e.Links, nil
+	}
+	return nil, nil
+}
+
+// writeFile returns a function suitable for mdlinks.Fixer.WriteFile that
+// saves files inside opts.dir, keeping their permissions. If dryRun is set,
+// it does nothing.
+func (opts *options) writeFile(dryRun bool) func(name string, data []byte) error {
+	return func(name string, data []byte) error {
+		if dryRun {
+			return nil
+		}
+		name = opts.localPath(name)
+		fi, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, data, fi.Mode().Perm())
+	}
+}
+
+// printFixes writes fixes to stdout, one per line.
+func printFixes(fixes []mdlinks.Fix) {
+	for _, fx := range fixes {
+		fmt.Printf("%s:%d: %q -> %q\n", fx.File, fx.Line, fx.Old, fx.New)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveFS_zipClose(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "docs.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys, closer, err := archiveFS(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(fsys, "a.md"); err != nil || string(b) != "# A\n" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// closing the zip.ReadCloser closes the archive file, so closing it
+	// again fails
+	if err := closer.Close(); err == nil {
+		t.Fatal("second Close succeeded, the archive file wasn't closed by the first one")
+	}
+}
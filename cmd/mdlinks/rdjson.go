@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Reviewdog Diagnostic Format, see
+// https://github.com/reviewdog/reviewdog/tree/master/proto/rdf
+type rdResult struct {
+	Source      rdSource       `json:"source"`
+	Severity    string         `json:"severity"`
+	Diagnostics []rdDiagnostic `json:"diagnostics"`
+}
+
+type rdSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdDiagnostic struct {
+	Message     string         `json:"message"`
+	Location    rdLocation     `json:"location"`
+	Severity    string         `json:"severity"`
+	Source      *rdSource      `json:"source,omitempty"`
+	Code        *rdCode        `json:"code,omitempty"`
+	Suggestions []rdSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdLocation struct {
+	Path  string   `json:"path"`
+	Range *rdRange `json:"range,omitempty"`
+}
+
+type rdRange struct {
+	Start rdPosition  `json:"start"`
+	End   *rdPosition `json:"end,omitempty"`
+}
+
+type rdPosition struct {
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdCode struct {
+	Value string `json:"value"`
+}
+
+type rdSuggestion struct {
+	Range rdRange `json:"range"`
+	Text  string  `json:"text"`
+}
+
+var rdMdlinks = rdSource{Name: "mdlinks", URL: "https://github.com/artyom/mdlinks"}
+
+// rdDiagnostics returns diagnostics of broken links, with paths relative to
+// the current directory, so that reviewdog can match them to the diff.
+func rdDiagnostics(res *result) []rdDiagnostic {
+	out := make([]rdDiagnostic, 0, len(res.links))
+	for _, l := range res.links {
+		d := rdDiagnostic{
+			Message:  l.String(),
+			Location: rdLocation{Path: filepath.ToSlash(filepath.Join(res.dir, l.File))},
+			Severity: strings.ToUpper(severity(l)),
+			Code:     &rdCode{Value: l.Kind()},
+		}
+		switch {
+		case l.Link.Line != 0:
+			r := rdRange{
+				Start: rdPosition{Line: l.Link.Line, Column: l.Link.Column},
+				End:   &rdPosition{Line: l.Link.Line, Column: l.Link.Column + len(l.Link.Raw)},
+			}
+			d.Location.Range = &r
+			if l.Suggestion != "" {
+				d.Suggestions = []rdSuggestion{{Range: r, Text: l.Suggestion}}
+			}
+		case l.Link.LineStart != 0:
+			d.Location.Range = &rdRange{
+				Start: rdPosition{Line: l.Link.LineStart},
+				End:   &rdPosition{Line: l.Link.LineEnd},
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// reportRDJSON writes results as a single Reviewdog Diagnostic Format
+// document (rdjson).
+func reportRDJSON(w io.Writer, res *result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rdResult{
+		Source:      rdMdlinks,
+		Severity:    "ERROR",
+		Diagnostics: rdDiagnostics(res),
+	})
+}
+
+// reportRDJSONL writes results as a stream of Reviewdog Diagnostic Format
+// diagnostics, one per line (rdjsonl).
+func reportRDJSONL(w io.Writer, res *result) error {
+	enc := json.NewEncoder(w)
+	for _, d := range rdDiagnostics(res) {
+		d.Source = &rdMdlinks
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// cloneRepo makes a shallow clone of the git repository spec, like
+// “https://github.com/org/repo@branch”, into a new temporary directory, and
+// returns its path. The “@branch” part is optional, and may also name a tag;
+// without it the default branch is cloned. It needs git to be installed.
+func cloneRepo(spec string) (string, error) {
+	url, ref := spec, ""
+	if i := strings.LastIndexByte(spec, '@'); i > strings.LastIndexAny(spec, "/:") {
+		url, ref = spec[:i], spec[i+1:]
+	}
+	dir, err := os.MkdirTemp("", "mdlinks-")
+	if err != nil {
+		return "", err
+	}
+	args := []string{"clone", "--quiet", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	cmd := exec.Command("git", append(args, "--", url, dir)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("cloning %s: %w", url, err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/artyom/mdlinks"
+)
+
+// result holds the outcome of a single check run.
+type result struct {
+	dir   string   // scanned directory, which file paths are relative to
+	files []string // checked files, in traversal order
+	links []mdlinks.BrokenLink
+
+	// source, if not nil, is used by the text report to show source lines
+	// of broken links
+	source *sources
+}
+
+type fileLinks struct {
+	file  string
+	links []mdlinks.BrokenLink
+}
+
+// perFile groups broken links by checked file, keeping files in traversal
+// order; files without broken links are included too.
+func (res *result) perFile() []fileLinks {
+	byFile := make(map[string][]mdlinks.BrokenLink)
+	for _, l := range res.links {
+		byFile[l.File] = append(byFile[l.File], l)
+	}
+	out := make([]fileLinks, 0, len(res.files))
+	for _, f := range res.files {
+		out = append(out, fileLinks{file: f, links: byFile[f]})
+		delete(byFile, f)
+	}
+	for _, l := range res.links { // in case broken links reference unlisted files
+		if links, ok := byFile[l.File]; ok {
+			out = append(out, fileLinks{file: l.File, links: links})
+			delete(byFile, l.File)
+		}
+	}
+	return out
+}
+
+// reporter writes check results to w in a specific format.
+type reporter func(w io.Writer, res *result) error
+
+var reporters = map[string]reporter{
+	"text":  reportText,
+	"json":  reportJSON,
+	"junit": reportJUnit,
+	"tap":   reportTAP,
+	"short": reportShort,
+
+	"codeclimate":   reportCodeClimate,
+	"github-review": reportGitHubReview,
+	"rdjson":        reportRDJSON,
+	"rdjsonl":       reportRDJSONL,
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(reporters))
+	for k := range reporters {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// reportText logs broken links as human-readable lines; it ignores w, as
+// these lines were historically written to stderr.
+func reportText(_ io.Writer, res *result) error { return writeText(res, false) }
+
+func writeText(res *result, p palette) error {
+	for _, l := range res.links {
+		if l.IsWarning() {
+			log.Println(p.yellow("warning:"), p.message(l))
+		} else {
+			log.Println(p.message(l))
+		}
+		if res.source != nil {
+			fmt.Fprint(log.Writer(), res.source.snippet(l, p))
+		}
+	}
+	return nil
+}
+
+// reportShort writes broken links as “file:line:col: message” lines
+// understood by editors, like Vim quickfix or Emacs compilation mode.
+func reportShort(w io.Writer, res *result) error {
+	for _, l := range res.links {
+		// editors expect paths relative to the current directory
+		pos := filepath.Join(res.dir, filepath.FromSlash(l.File))
+		switch {
+		case l.Link.Cell != 0:
+		case l.Link.Line != 0:
+			pos = fmt.Sprintf("%s:%d:%d", pos, l.Link.Line, l.Link.Column)
+		case l.Link.LineStart != 0:
+			pos = fmt.Sprintf("%s:%d", pos, l.Link.LineStart)
+		}
+		msg := cellLocation(l) + l.Message()
+		if l.IsWarning() {
+			msg = "warning: " + msg
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", pos, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellLocation returns the location of the broken link l in a Jupyter
+// notebook cell, like “cell 2, line 3: ”, as positions of such links are
+// relative to their cells, and can't be reported as file positions. For
+// other links it returns an empty string.
+func cellLocation(l mdlinks.BrokenLink) string {
+	switch {
+	case l.Link.Cell == 0:
+		return ""
+	case l.Link.Line != 0:
+		return fmt.Sprintf("cell %d, line %d: ", l.Link.Cell, l.Link.Line)
+	}
+	return fmt.Sprintf("cell %d: ", l.Link.Cell)
+}
+
+// severity returns either "error" or "warning".
+func severity(l mdlinks.BrokenLink) string {
+	if l.IsWarning() {
+		return "warning"
+	}
+	return "error"
+}
+
+type jsonLink struct {
+	File      string `json:"file"`
+	Link      string `json:"link"`
+	Path      string `json:"path,omitempty"`
+	Fragment  string `json:"fragment,omitempty"`
+	Query     string `json:"query,omitempty"`
+	LineStart int    `json:"lineStart,omitempty"`
+	LineEnd   int    `json:"lineEnd,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Cell      int    `json:"cell,omitempty"`
+	Kind      string `json:"kind"`
+	Severity  string `json:"severity"`
+	Reason    string `json:"reason"`
+
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+func reportJSON(w io.Writer, res *result) error {
+	out := make([]jsonLink, 0, len(res.links))
+	for _, l := range res.links {
+		out = append(out, jsonLink{
+			File:      l.File,
+			Link:      l.Link.Raw,
+			Path:      l.Link.Path,
+			Fragment:  l.Link.Fragment,
+			Query:     l.Link.Query,
+			LineStart: l.Link.LineStart,
+			LineEnd:   l.Link.LineEnd,
+			Line:      l.Link.Line,
+			Column:    l.Link.Column,
+			Cell:      l.Link.Cell,
+			Kind:      l.Kind(),
+			Severity:  severity(l),
+			Reason:    l.Reason(),
+
+			Suggestion: l.Suggestion,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// reportTAP writes results in the Test Anything Protocol format, with one test
+// point per checked file and a diagnostic line for each of its broken links.
+func reportTAP(w io.Writer, res *result) error {
+	files := res.perFile()
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(files))
+	for i, fl := range files {
+		if len(fl.links) == 0 {
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, fl.file)
+			continue
+		}
+		status := "ok"
+		for _, l := range fl.links {
+			if !l.IsWarning() {
+				status = "not ok"
+				break
+			}
+		}
+		fmt.Fprintf(&b, "%s %d - %s\n", status, i+1, fl.file)
+		for _, l := range fl.links {
+			fmt.Fprintf(&b, "# %s: %s\n", severity(l), l)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/artyom/mdlinks"
+)
+
+// testResult returns the result of checking the test documents, as if they
+// were stored in the docs directory.
+func testResult(t *testing.T) *result {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"guide/a.md": &fstest.MapFile{Data: []byte("# A\n\n[gone](missing.md)\n")},
+	}
+	c := &mdlinks.Checker{Matcher: func(string) (bool, error) { return true, nil }}
+	links, err := brokenLinks(fsys, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &result{dir: "docs", files: []string{"guide/a.md"}, links: links}
+}
+
+func TestReportCodeClimate_paths(t *testing.T) {
+	var buf bytes.Buffer
+	if err := reportCodeClimate(&buf, testResult(t)); err != nil {
+		t.Fatal(err)
+	}
+	var issues []ccIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Location.Path != "docs/guide/a.md" {
+		t.Fatalf("got %+v, want a single issue in docs/guide/a.md", issues)
+	}
+}
+
+func TestReportRDJSON_paths(t *testing.T) {
+	var buf bytes.Buffer
+	if err := reportRDJSON(&buf, testResult(t)); err != nil {
+		t.Fatal(err)
+	}
+	var out rdResult
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Diagnostics) != 1 || out.Diagnostics[0].Location.Path != "docs/guide/a.md" {
+		t.Fatalf("got %+v, want a single diagnostic in docs/guide/a.md", out.Diagnostics)
+	}
+}
+
+func TestReportShort_notebook(t *testing.T) {
+	res := &result{dir: "docs", links: []mdlinks.BrokenLink{
+		{File: "nb.ipynb", Link: mdlinks.LinkInfo{Raw: "gone.md", Path: "gone.md", Line: 3, Column: 8, LineStart: 3, LineEnd: 3, Cell: 2}},
+	}}
+	var buf bytes.Buffer
+	if err := reportShort(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join("docs", "nb.ipynb") + ": cell 2, line 3: link \"gone.md\" points to a non-existing file\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/artyom/mdlinks"
+)
+
+// runServe implements the “serve” subcommand: an HTTP server checking the
+// directory on request, and serving the latest report.
+func runServe(args []string) error {
+	fset := flag.NewFlagSet("mdlinks serve", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: mdlinks serve [flags]\n\n"+
+			"Serves HTTP endpoints checking the directory and reporting the results:\n\n"+
+			"\tPOST /check\truns a check and responds with its JSON report\n"+
+			"\tGET /report\tresponds with the latest report, JSON or, with ?format=html, HTML\n"+
+			"\tGET /healthz\tresponds with 200 OK\n"+
+			"\tGET /metrics\tresponds with metrics in the Prometheus text format\n\n"+
+			"The directory is checked once on start, and then on each POST /check request.")
+		fset.PrintDefaults()
+	}
+	opts := newOptions()
+	opts.register(fset)
+	addr := fset.String("listen", ":8080", "`address` to listen at")
+	fset.Parse(args)
+	if len(opts.moreDirs) != 0 {
+		return errors.New("-dir can only be used once with serve")
+	}
+	srv := &server{opts: opts}
+	if err := srv.check(); err != nil {
+		log.Print(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/check", srv.handleCheck)
+	mux.HandleFunc("/report", srv.handleReport)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, "ok") })
+	mux.HandleFunc("/metrics", srv.handleMetrics)
+	log.Printf("serving %s at %s", opts.dir, *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// server runs checks for the serve subcommand, keeping the latest report.
+type server struct {
+	opts *options
+
+	checkMu sync.Mutex // serializes checks
+
+	mu       sync.Mutex // guards the fields below
+	last     *result    // latest successful check, nil before the first one
+	lastTime time.Time  // when the last successful check finished
+	duration time.Duration
+	checks   int // number of checks run, including failed ones
+	failures int // number of checks failed with an error
+}
+
+// check checks the directory, replacing the latest report on success.
+func (srv *server) check() error {
+	srv.checkMu.Lock()
+	defer srv.checkMu.Unlock()
+	start := time.Now()
+	res, err := srv.run()
+	duration := time.Since(start)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.checks++
+	if err != nil {
+		srv.failures++
+		return err
+	}
+	srv.last, srv.lastTime, srv.duration = res, time.Now(), duration
+	return nil
+}
+
+// run checks the directory with a fresh Checker, so that changes of the
+// configuration files are picked up too.
+func (srv *server) run() (*result, error) {
+	var files []string
+	fsys, c, closer, err := srv.opts.checker(&files)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	links, err := brokenLinks(fsys, c)
+	if err != nil {
+		return nil, err
+	}
+	return &result{dir: srv.opts.dir, files: files, links: links}, nil
+}
+
+// latest returns the latest report, or nil if no check succeeded yet.
+func (srv *server) latest() *result {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.last
+}
+
+func (srv *server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST to run a check", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := srv.check(); err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	srv.writeReport(w, srv.latest(), "json")
+}
+
+func (srv *server) handleReport(w http.ResponseWriter, r *http.Request) {
+	res := srv.latest()
+	if res == nil {
+		http.Error(w, "no successful check yet", http.StatusNotFound)
+		return
+	}
+	srv.writeReport(w, res, r.FormValue("format"))
+}
+
+// writeReport writes res as JSON or, if format is "html", as HTML.
+func (srv *server) writeReport(w http.ResponseWriter, res *result, format string) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		err = reportJSON(&buf, res)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		type fileReport struct {
+			File  string
+			Links []mdlinks.BrokenLink
+		}
+		var files []fileReport
+		for _, fl := range res.perFile() {
+			if len(fl.links) != 0 {
+				files = append(files, fileReport{File: fl.file, Links: fl.links})
+			}
+		}
+		err = reportTemplate.Execute(&buf, files)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported format %q, supported values are: json, html", format), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+func (srv *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	checks, failures := srv.checks, srv.failures
+	res, lastTime, duration := srv.last, srv.lastTime, srv.duration
+	srv.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP mdlinks_checks_total Checks run, including failed ones.\n"+
+		"# TYPE mdlinks_checks_total counter\nmdlinks_checks_total %d\n", checks)
+	fmt.Fprintf(w, "# HELP mdlinks_check_failures_total Checks failed with an error.\n"+
+		"# TYPE mdlinks_check_failures_total counter\nmdlinks_check_failures_total %d\n", failures)
+	if res == nil {
+		return
+	}
+	var errs, warnings int
+	for _, l := range res.links {
+		if l.IsWarning() {
+			warnings++
+		} else {
+			errs++
+		}
+	}
+	fmt.Fprintf(w, "# HELP mdlinks_broken_links Broken links found by the latest check, by severity.\n"+
+		"# TYPE mdlinks_broken_links gauge\n"+
+		"mdlinks_broken_links{severity=\"error\"} %d\nmdlinks_broken_links{severity=\"warning\"} %d\n", errs, warnings)
+	fmt.Fprintf(w, "# HELP mdlinks_checked_files Files checked by the latest check.\n"+
+		"# TYPE mdlinks_checked_files gauge\nmdlinks_checked_files %d\n", len(res.files))
+	fmt.Fprintf(w, "# HELP mdlinks_last_check_duration_seconds Duration of the latest check.\n"+
+		"# TYPE mdlinks_last_check_duration_seconds gauge\nmdlinks_last_check_duration_seconds %g\n", duration.Seconds())
+	fmt.Fprintf(w, "# HELP mdlinks_last_check_timestamp_seconds When the latest check finished, as a Unix time.\n"+
+		"# TYPE mdlinks_last_check_timestamp_seconds gauge\nmdlinks_last_check_timestamp_seconds %d\n", lastTime.Unix())
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"severity": severity,
+}).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>mdlinks report</title>
+<style>body{font-family:sans-serif} .error{color:#b00} .warning{color:#a60} td{padding:0 1em 0 0}</style>
+</head><body>
+<h1>mdlinks report</h1>
+{{range .}}<h2>{{.File}}</h2>
+<table>{{range .Links}}
+<tr class="{{severity .}}"><td>{{.Link.Line}}:{{.Link.Column}}</td><td><code>{{.Link.Raw}}</code></td><td>{{.Reason}}</td><td>{{with .Suggestion}}did you mean <code>{{.}}</code>?{{end}}</td></tr>{{end}}
+</table>{{else}}<p>No broken links found.</p>{{end}}
+</body></html>
+`))
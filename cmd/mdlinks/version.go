@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// writeVersion writes the module version, the VCS revision the binary was
+// built from, and versions of the Go toolchain and goldmark, so that bug
+// reports and CI logs tell which mdlinks produced the result.
+func writeVersion(w io.Writer) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		fmt.Fprintln(w, "mdlinks: no build info")
+		return
+	}
+	fmt.Fprintf(w, "mdlinks %s\n", info.Main.Version)
+	settings := make(map[string]string)
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if rev := settings["vcs.revision"]; rev != "" {
+		if settings["vcs.modified"] == "true" {
+			rev += " (modified)"
+		}
+		if t := settings["vcs.time"]; t != "" {
+			rev += " " + t
+		}
+		fmt.Fprintf(w, "revision: %s\n", rev)
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/yuin/goldmark" {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		fmt.Fprintf(w, "goldmark: %s\n", dep.Version)
+	}
+	fmt.Fprintf(w, "go: %s\n", info.GoVersion)
+}
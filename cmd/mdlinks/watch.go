@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/artyom/mdlinks"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long -watch waits for more changes after a file change,
+// as editors often save files in several steps.
+const watchDelay = 100 * time.Millisecond
+
+// watch checks all documents of fsys, the filesystem of the directory dir,
+// with c, then keeps watching dir for changed files, re-checking documents
+// affected by the changes. It calls report with paths of documents checked
+// in each round, and broken links found in them. It only returns on error.
+func watch(dir string, fsys fs.FS, c *mdlinks.Checker, report func(files []string, links []mdlinks.BrokenLink) error) error {
+	if fi, err := os.Stat(dir); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return fmt.Errorf("-watch needs a directory, %s is not one", dir)
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	tree := &watchedTree{dir: dir, w: w, files: make(map[string]bool), dirs: make(map[string]bool)}
+	if _, err := tree.add("."); err != nil {
+		return err
+	}
+	s, err := c.NewSession(fsys)
+	if err != nil {
+		return err
+	}
+	files, links, err := s.Check()
+	if err != nil {
+		return err
+	}
+	if err := report(files, links); err != nil {
+		return err
+	}
+	changed := make(map[string]bool)
+	var settled <-chan time.Time // fires once changes stop for watchDelay
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return errors.New("file watcher stopped")
+			}
+			paths, err := tree.event(ev)
+			if err != nil {
+				return err
+			}
+			for _, p := range paths {
+				changed[p] = true
+			}
+			if len(changed) != 0 {
+				settled = time.After(watchDelay)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return errors.New("file watcher stopped")
+			}
+			return err
+		case <-settled:
+			settled = nil
+			names := make([]string, 0, len(changed))
+			for p := range changed {
+				names = append(names, p)
+			}
+			sort.Strings(names)
+			changed = make(map[string]bool)
+			if files, links, err = s.Update(names...); err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				continue
+			}
+			log.Printf("%d files changed, re-checked %d documents", len(names), len(files))
+			if err := report(files, links); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchedTree keeps the fsnotify watcher subscribed to all directories of
+// the tree, as fsnotify doesn't watch subdirectories, and translates its
+// events to changed fs.FS paths.
+type watchedTree struct {
+	dir   string
+	w     *fsnotify.Watcher
+	files map[string]bool // known regular files, by their fs.FS paths
+	dirs  map[string]bool // watched directories, by their fs.FS paths
+}
+
+// add starts watching the directory p, which is an fs.FS path, with its
+// subdirectories, skipping .git ones. It returns paths of files found, which
+// may have been created before the watch started. Files and directories
+// removed while walking are skipped.
+func (t *watchedTree) add(p string) ([]string, error) {
+	var found []string
+	root := filepath.Join(t.dir, filepath.FromSlash(p))
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) { // removed while walking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := t.rel(name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return fs.SkipDir
+		case d.IsDir():
+			if err := t.w.Add(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			t.dirs[rel] = true
+		case d.Type().IsRegular():
+			t.files[rel] = true
+			found = append(found, rel)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// event returns fs.FS paths of files changed according to ev, starting to
+// watch created directories, and forgetting removed ones.
+func (t *watchedTree) event(ev fsnotify.Event) ([]string, error) {
+	p, err := t.rel(ev.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".git" {
+			return nil, nil
+		}
+	}
+	switch {
+	case ev.Has(fsnotify.Create):
+		fi, err := os.Lstat(ev.Name)
+		if errors.Is(err, fs.ErrNotExist) { // already removed
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			found, err := t.add(p)
+			return append(found, p), err
+		}
+		t.files[p] = true
+		return []string{p}, nil
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		// renamed files are reported with their old names, the new ones
+		// come with Create events
+		if !t.dirs[p] {
+			delete(t.files, p)
+			return []string{p}, nil
+		}
+		t.w.Remove(ev.Name) // fails if the directory is gone, which is fine
+		out := []string{p}
+		prefix := p + "/"
+		for name := range t.files {
+			if strings.HasPrefix(name, prefix) {
+				delete(t.files, name)
+				out = append(out, name)
+			}
+		}
+		for name := range t.dirs {
+			if strings.HasPrefix(name, prefix) {
+				t.w.Remove(filepath.Join(t.dir, filepath.FromSlash(name)))
+				delete(t.dirs, name)
+			}
+		}
+		delete(t.dirs, p)
+		return out, nil
+	case ev.Has(fsnotify.Write):
+		return []string{p}, nil
+	}
+	return nil, nil
+}
+
+// rel returns the fs.FS path of the file name, as reported by fsnotify.
+func (t *watchedTree) rel(name string) (string, error) {
+	rel, err := filepath.Rel(t.dir, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchedTree(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", ".git/HEAD"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	tree := &watchedTree{dir: dir, w: w, files: make(map[string]bool), dirs: make(map[string]bool)}
+	found, err := tree.add(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(found, " ") != "a.md" {
+		t.Fatalf("add: got %q, want only a.md", found)
+	}
+	// files created along with the directory, before it's watched
+	sub := filepath.Join(dir, "sub", "deep")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "b.md"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, ev fsnotify.Event, want string) {
+		t.Helper()
+		got, err := tree.event(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	check("created directory", fsnotify.Event{Name: filepath.Join(dir, "sub"), Op: fsnotify.Create}, "sub sub/deep/b.md")
+	check("written file", fsnotify.Event{Name: filepath.Join(dir, "a.md"), Op: fsnotify.Write}, "a.md")
+	check("git file", fsnotify.Event{Name: filepath.Join(dir, ".git", "HEAD"), Op: fsnotify.Write}, "")
+	if err := os.RemoveAll(filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	check("removed directory", fsnotify.Event{Name: filepath.Join(dir, "sub"), Op: fsnotify.Remove}, "sub sub/deep/b.md")
+	if len(tree.dirs) != 1 || !tree.dirs["."] {
+		t.Errorf("watched directories after removal: %v, want only the root", tree.dirs)
+	}
+}
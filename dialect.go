@@ -0,0 +1,57 @@
+package mdlinks
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+// Dialect selects the flavor of markdown documents are parsed as. It affects
+// which syntax is recognized as links, like bare URLs, and which anchors
+// headers get, like explicit “{#custom-id}” ones.
+type Dialect byte
+
+const (
+	// DialectDefault is GitHub Flavored Markdown with footnotes and
+	// explicit header ids, like “## Install {#install}”.
+	DialectDefault Dialect = iota
+
+	// DialectCommonMark is plain CommonMark, without tables, bare URL
+	// autolinks, footnotes, or explicit header ids.
+	DialectCommonMark
+
+	// DialectGFM is GitHub Flavored Markdown with footnotes, as GitHub
+	// renders it; explicit header ids are not recognized.
+	DialectGFM
+
+	// DialectGoldmark is GitHub Flavored Markdown with common goldmark
+	// extensions: footnotes, definition lists, and explicit header ids.
+	DialectGoldmark
+)
+
+// newParser returns a parser for the markdown dialect d with additional
+// extensions and options; if wiki is set, it also recognizes wiki-style
+// links.
+func newParser(d Dialect, wiki bool, extensions []goldmark.Extender, options []parser.Option) parser.Parser {
+	var exts []goldmark.Extender
+	var popts []parser.Option
+	switch d {
+	case DialectCommonMark:
+	case DialectGFM:
+		exts = []goldmark.Extender{extension.GFM, extension.Footnote}
+	case DialectGoldmark:
+		exts = []goldmark.Extender{extension.GFM, extension.Footnote, extension.DefinitionList}
+		popts = []parser.Option{parser.WithHeadingAttribute()}
+	default:
+		exts = []goldmark.Extender{extension.GFM, extension.Footnote}
+		popts = []parser.Option{parser.WithHeadingAttribute()}
+	}
+	if wiki {
+		popts = append(popts, parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 150)))
+	}
+	return goldmark.New(
+		goldmark.WithExtensions(append(exts, extensions...)...),
+		goldmark.WithParserOptions(append(popts, options...)...),
+	).Parser()
+}
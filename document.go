@@ -0,0 +1,93 @@
+package mdlinks
+
+import (
+	"errors"
+	"io/fs"
+	"unicode/utf8"
+)
+
+// DocInfo describes a markdown document parsed by ParseDocument.
+type DocInfo struct {
+	// Links are all links and images of the document, including the ones
+	// in raw HTML, in the document order. Path and Fragment are only set
+	// for local links; links without destinations have empty Raw.
+	Links []LinkInfo
+
+	// Anchors are header slugs and ids of HTML elements, that links to the
+	// document can have as fragments, in the document order.
+	Anchors []Anchor
+
+	// FrontMatter holds parsed YAML or TOML front matter, if any.
+	FrontMatter map[string]any
+}
+
+// Anchor is a fragment identifier of a location inside the document.
+type Anchor struct {
+	ID    string // header slug or HTML element id, without '#'
+	Level int    // header level, 1 to 6; 0 for HTML elements
+	Text  string // plain header text; empty for HTML elements
+
+	// Line and Column are the 1-based line number and byte column of the
+	// header line, or of the id attribute value of the HTML element;
+	// Offset is the 0-based byte offset of the same position.
+	Line, Column, Offset int
+
+	// Cell is the 1-based index of the Jupyter notebook cell the anchor is
+	// found in, see LinkInfo.Cell.
+	Cell int
+}
+
+// ParseDocument parses markdown document body the same way CheckFS does,
+// and returns its links and anchors.
+func ParseDocument(body []byte) (*DocInfo, error) {
+	if !utf8.Valid(body) {
+		return nil, errors.New("mdlinks: document is not a valid utf8 text")
+	}
+	d, err := extractDocDetails(body, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DocInfo{Links: d.all, Anchors: d.anchorList, FrontMatter: d.frontMatter}, nil
+}
+
+// Anchors returns anchors of the document name of fsys, in the document
+// order, parsing it the same way CheckFS does. For HTML files, which name
+// is not matched by Matcher, only IDs of anchors are set. For other files
+// it returns nil.
+func (c *Checker) Anchors(fsys fs.FS, name string) ([]Anchor, error) {
+	if c == nil {
+		panic("mdlinks: Anchors called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: Anchors called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	switch ok, err := c.Matcher(name); {
+	case err != nil:
+		return nil, err
+	case ok:
+		d, err := st.fileMeta(name)
+		if err != nil {
+			return nil, err
+		}
+		return d.anchorList, nil
+	case isHTMLFile(name):
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var out []Anchor
+		seen := make(map[string]bool)
+		for _, id := range htmlAnchors(b) {
+			if !seen[id] {
+				seen[id] = true
+				out = append(out, Anchor{ID: id})
+			}
+		}
+		return out, nil
+	}
+	return nil, nil
+}
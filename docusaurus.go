@@ -0,0 +1,77 @@
+package mdlinks
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// docusaurusExts are extensions tried for extensionless links.
+var docusaurusExts = []string{".md", ".mdx"}
+
+// resolveDocusaurus resolves a link to a non-existing fsys path p the way
+// Docusaurus does: p may omit the document extension, point to a directory
+// with an index document, or match a document by its front matter slug or
+// id. It returns an empty string if p cannot be resolved.
+func (st *checkState) resolveDocusaurus(p string) (string, error) {
+	p = strings.TrimSuffix(p, "/")
+	for _, ext := range docusaurusExts {
+		for _, cand := range []string{p + ext, path.Join(p, "index"+ext)} {
+			if st.exists(cand) {
+				return cand, nil
+			}
+		}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.slugIndex == nil {
+		if err := st.buildSlugIndex(); err != nil {
+			return "", err
+		}
+	}
+	return st.slugIndex[p], nil
+}
+
+// buildSlugIndex indexes all documents matched by Matcher by their Docusaurus
+// url paths, as derived from their front matter slug and id fields.
+func (st *checkState) buildSlugIndex() error {
+	index := make(map[string]string)
+	fn := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ok, _ := st.c.Matcher(p); !ok {
+			return nil
+		}
+		b, err := fs.ReadFile(st.fsys, p)
+		if err != nil {
+			return err
+		}
+		var slug, id string
+		if fm := findFrontMatter(b); fm != nil {
+			slug, _ = fm.values["slug"].(string)
+			id, _ = fm.values["id"].(string)
+		}
+		dir := path.Dir(p)
+		switch {
+		case strings.HasPrefix(slug, "/"):
+			index[strings.Trim(slug, "/")] = p
+		case slug != "":
+			index[path.Join(dir, strings.TrimSuffix(slug, "/"))] = p
+		case id != "":
+			index[path.Join(dir, id)] = p
+		}
+		return nil
+	}
+	if err := fs.WalkDir(st.fsys, ".", fn); err != nil {
+		return err
+	}
+	st.slugIndex = index
+	return nil
+}
@@ -0,0 +1,104 @@
+package mdlinks
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// isExternalLink reports whether s is an absolute http(s) url.
+func isExternalLink(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// checkExternal requests urls of the given links and returns only those links
+// that point to unreachable resources. Each unique url (with its fragment
+// removed) is requested only once.
+func (c *Checker) checkExternal(links []BrokenLink) []BrokenLink {
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	workers := c.ExternalConcurrency
+	if workers <= 0 {
+		workers = 8
+	}
+	dead := make(map[string]bool)
+	var unique []string
+	for _, l := range links {
+		u := stripFragment(l.Link.Raw)
+		if _, ok := dead[u]; !ok {
+			dead[u] = false
+			unique = append(unique, u)
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	urls := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range urls {
+				ok := urlAlive(client, u)
+				mu.Lock()
+				dead[u] = !ok
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, u := range unique {
+		urls <- u
+	}
+	close(urls)
+	wg.Wait()
+
+	var out []BrokenLink
+	for _, l := range links {
+		if dead[stripFragment(l.Link.Raw)] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// urlAlive reports whether resource at u can be fetched. It first tries a HEAD
+// request, falling back to GET for servers that don't support HEAD.
+func urlAlive(client *http.Client, u string) bool {
+	code, err := requestStatus(client, http.MethodHead, u)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented || code == http.StatusForbidden) {
+		code, err = requestStatus(client, http.MethodGet, u)
+	}
+	if err != nil {
+		return false
+	}
+	// rate limiting tells nothing about the resource itself
+	return code < 400 || code == http.StatusTooManyRequests
+}
+
+func requestStatus(client *http.Client, method, u string) (int, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "mdlinks (https://github.com/artyom/mdlinks)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func stripFragment(s string) string {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
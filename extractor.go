@@ -0,0 +1,88 @@
+package mdlinks
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// LinkExtractor finds links of a custom syntax, like templating macros or
+// include directives, in a matched document, see Checker.Extractors.
+//
+// The source is the document body with its front matter, if any, replaced
+// with spaces, so that offsets into it match offsets into the file; files
+// that are not markdown are passed as is. The doc
+// is the root of the document parsed by goldmark, or nil for files that are
+// not markdown, see Checker.FileExtractors.
+//
+// Extractor only has to set the Raw and Offset fields of the returned links:
+// Raw is the link destination, as it would be written in a markdown link, and
+// Offset is the byte offset of its first byte in the source. The rest of the
+// fields are filled by the Checker.
+type LinkExtractor func(source []byte, doc ast.Node) ([]LinkInfo, error)
+
+// RegexpExtractor returns a LinkExtractor reporting the first capturing group
+// of every match of re as a link, or the whole match if re has no groups, like
+// “href="([^"]+)"” for HTML files. Empty groups are skipped.
+func RegexpExtractor(re *regexp.Regexp) LinkExtractor {
+	return func(source []byte, _ ast.Node) ([]LinkInfo, error) {
+		var out []LinkInfo
+		for _, m := range re.FindAllSubmatchIndex(source, -1) {
+			start, end := m[0], m[1]
+			if len(m) > 2 {
+				start, end = m[2], m[3]
+			}
+			if start < end {
+				out = append(out, LinkInfo{Raw: string(source[start:end]), Offset: start})
+			}
+		}
+		return out, nil
+	}
+}
+
+// extractedLink returns the link x found by a LinkExtractor in body with its
+// position fields filled. It returns false if x has no destination or its
+// offset is outside of body.
+func extractedLink(body []byte, x LinkInfo) (LinkInfo, bool) {
+	if x.Raw == "" || x.Offset < 0 || x.Offset > len(body) {
+		return LinkInfo{}, false
+	}
+	l := LinkInfo{Raw: x.Raw}
+	l.setOffset(body, x.Offset)
+	l.LineStart, l.LineEnd = l.Line, l.Line
+	return l, true
+}
+
+// extractFileDetails returns details of the file p with the body, which
+// links are found by extract, see Checker.FileExtractors. Anchors are only
+// filled for HTML files.
+func extractFileDetails(p string, body []byte, extract LinkExtractor) (*docDetails, error) {
+	links, err := extract(body, nil)
+	if err != nil {
+		return nil, err
+	}
+	d := &docDetails{anchors: make(map[string]struct{})}
+	if isHTMLFile(p) {
+		for _, id := range htmlAnchors(body) {
+			d.anchors[id] = struct{}{}
+		}
+	}
+	for _, x := range links {
+		l, ok := extractedLink(body, x)
+		if !ok {
+			continue
+		}
+		if u := localLink(l.Raw); u != nil {
+			l.Path, l.Fragment = u.Path, u.Fragment
+			d.links = append(d.links, l)
+		} else if isExternalLink(l.Raw) {
+			d.external = append(d.external, l)
+		}
+		d.all = append(d.all, l)
+	}
+	for _, links := range [][]LinkInfo{d.links, d.external, d.all} {
+		sort.SliceStable(links, func(i, j int) bool { return links[i].Offset < links[j].Offset })
+	}
+	return d, nil
+}
@@ -0,0 +1,199 @@
+package mdlinks
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// Fixer rewrites broken links with their suggested replacements, see
+// BrokenLink.Suggestion. Suggestions are only made when there's a single best
+// candidate: a file with the mismatched case, a file with the same or similar
+// name found elsewhere in the tree, or the closest existing anchor.
+//
+// Usage example:
+//
+//	fsys := os.DirFS(dir)
+//	err := checker.CheckFS(fsys)
+//	var e *mdlinks.BrokenLinksError
+//	if errors.As(err, &e) {
+//	    f := &mdlinks.Fixer{WriteFile: func(name string, data []byte) error {
+//	        return os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), data, 0666)
+//	    }}
+//	    fixes, err := f.Fix(fsys, e.Links)
+//	    ...
+//	}
+type Fixer struct {
+	// WriteFile is called to save the updated content of the document name,
+	// a path inside the filesystem passed to Fix. It must not be nil.
+	WriteFile func(name string, data []byte) error
+}
+
+// Fix describes a single link rewritten by Fixer.
+type Fix struct {
+	File string // file path, uses '/' as a separator
+	Line int    // number of the first line of the link context
+	Old  string // link as seen in the source before the fix
+	New  string // link after the fix
+}
+
+// Fix rewrites links in documents of fsys for each of the given broken links
+// that has a suggestion, leaving the rest of the documents intact. Links
+// must come from checking the same fsys. It returns the applied fixes in the
+// order of links.
+func (f *Fixer) Fix(fsys fs.FS, links []BrokenLink) ([]Fix, error) {
+	if f.WriteFile == nil {
+		return nil, errors.New("mdlinks: Fixer.WriteFile is nil")
+	}
+	var files []string
+	byFile := make(map[string][]BrokenLink)
+	for _, l := range links {
+		if l.Suggestion == "" || l.Suggestion == l.Link.Raw {
+			continue
+		}
+		if _, ok := byFile[l.File]; !ok {
+			files = append(files, l.File)
+		}
+		byFile[l.File] = append(byFile[l.File], l)
+	}
+	var out []Fix
+	for _, name := range files {
+		fixes, err := f.rewrite(fsys, name, byFile[name])
+		out = append(out, fixes...)
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// rewrite replaces links of the document name with their suggestions and
+// saves it, if anything was changed.
+func (f *Fixer) rewrite(fsys fs.FS, name string, links []BrokenLink) ([]Fix, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	b, fixes := fixLinks(b, links)
+	if len(fixes) == 0 {
+		return nil, nil
+	}
+	if err := f.WriteFile(name, b); err != nil {
+		return nil, err
+	}
+	return fixes, nil
+}
+
+// fixLinks replaces links in the document body with their suggestions,
+// looking for them in the lines of their context, and returns the updated
+// body along with the applied fixes.
+func fixLinks(body []byte, links []BrokenLink) ([]byte, []Fix) {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	var fixes []Fix
+	used := make(map[int]bool) // start offsets of edits
+	for _, l := range links {
+		line := l.Link.LineStart
+		if l.Link.Cell != 0 {
+			// positions are relative to the notebook cell source, look for
+			// the link in the whole file instead
+			l.Link.Line, l.Link.LineStart = 0, 0
+		}
+		j := -1
+		if off := l.Link.Offset; l.Link.Line != 0 && !used[off] && bytes.HasPrefix(body[off:], []byte(l.Link.Raw)) {
+			j = off
+		}
+		if j < 0 {
+			start, end := lineRange(body, l.Link.LineStart, l.Link.LineEnd)
+			j = findLink(body, start, end, l.Link.Raw, used)
+		}
+		if j < 0 {
+			// reference-style links are reported at the place of use, while
+			// their destinations are in definitions elsewhere
+			j = findLink(body, 0, len(body), l.Link.Raw, used)
+		}
+		if j < 0 {
+			continue
+		}
+		used[j] = true
+		edits = append(edits, edit{start: j, end: j + len(l.Link.Raw), text: l.Suggestion})
+		fixes = append(fixes, Fix{File: l.File, Line: line, Old: l.Link.Raw, New: l.Suggestion})
+	}
+	if len(edits) == 0 {
+		return body, nil
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var buf bytes.Buffer
+	var last int
+	for _, e := range edits {
+		buf.Write(body[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+	buf.Write(body[last:])
+	return buf.Bytes(), fixes
+}
+
+// findLink returns the offset of the first occurrence of the raw link in
+// body[start:end] that is not in used and looks like a link destination, or -1
+// if there's none.
+func findLink(body []byte, start, end int, raw string, used map[int]bool) int {
+	for i := start; i < end; {
+		j := bytes.Index(body[i:end], []byte(raw))
+		if j < 0 {
+			return -1
+		}
+		j += i
+		i = j + len(raw)
+		if !used[j] && linkBoundary(body, j, j+len(raw)) {
+			return j
+		}
+	}
+	return -1
+}
+
+// lineRange returns byte offsets of the start of line first and the end of
+// line last, 1-based. If first is 0, the whole body range is returned.
+func lineRange(body []byte, first, last int) (start, end int) {
+	if first == 0 {
+		return 0, len(body)
+	}
+	line := 1
+	start, end = -1, len(body)
+	for i := 0; i <= len(body); i++ {
+		if line == first && start < 0 {
+			start = i
+		}
+		if i == len(body) {
+			break
+		}
+		if body[i] == '\n' {
+			if line == last {
+				end = i
+				break
+			}
+			line++
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// linkBoundary reports whether body[start:end] is delimited the way link
+// destinations are, so that replacing it doesn't touch the surrounding
+// text, like in “(dest)”, “<dest>”, or “href="dest"”.
+func linkBoundary(body []byte, start, end int) bool {
+	if start == 0 || bytes.IndexByte([]byte("(<\"' \t="), body[start-1]) < 0 {
+		return false
+	}
+	return end == len(body) || bytes.IndexByte([]byte(")>\"' \t\r\n"), body[end]) >= 0
+}
@@ -0,0 +1,128 @@
+package mdlinks
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter describes a front matter block at the start of a document.
+type frontMatter struct {
+	raw    []byte // front matter without delimiters
+	toml   bool   // “+++”-delimited TOML front matter, otherwise YAML
+	end    int    // offset of the document content following front matter
+	values map[string]any
+}
+
+// findFrontMatter looks for the front matter block at the very start of the
+// document body: either YAML delimited by “---” lines, or TOML delimited by
+// “+++” lines. It returns nil if there is no front matter. Values are parsed
+// on a best effort basis: malformed front matter results in empty values.
+func findFrontMatter(body []byte) *frontMatter {
+	var delim string
+	switch {
+	case bytes.HasPrefix(body, []byte("---")):
+		delim = "---"
+	case bytes.HasPrefix(body, []byte("+++")):
+		delim = "+++"
+	default:
+		return nil
+	}
+	isDelim := func(line []byte) bool {
+		line = bytes.TrimRight(line, " \t\r")
+		return string(line) == delim || (delim == "---" && string(line) == "...")
+	}
+	first, after, ok := cutLine(body)
+	if !ok || string(bytes.TrimRight(first, " \t\r")) != delim {
+		return nil
+	}
+	for pos := after; pos < len(body); {
+		line, next, _ := cutLine(body[pos:])
+		if isDelim(line) {
+			fm := &frontMatter{raw: body[after:pos], toml: delim == "+++", end: pos + next}
+			fm.parse()
+			return fm
+		}
+		pos += next
+	}
+	return nil
+}
+
+func (fm *frontMatter) parse() {
+	var v map[string]any
+	var err error
+	if fm.toml {
+		err = toml.Unmarshal(fm.raw, &v)
+	} else {
+		err = yaml.Unmarshal(fm.raw, &v)
+	}
+	if err == nil {
+		fm.values = v
+	}
+}
+
+// blankFrontMatter returns a copy of body with its front matter block, if
+// any, replaced with empty lines, so that it's not parsed as markdown, while
+// offsets and line numbers of the rest of the document are preserved.
+func blankFrontMatter(body []byte, fm *frontMatter) []byte {
+	if fm == nil {
+		return body
+	}
+	out := make([]byte, len(body))
+	copy(out, body)
+	for i := 0; i < fm.end; i++ {
+		if out[i] != '\n' {
+			out[i] = ' '
+		}
+	}
+	return out
+}
+
+// cutLine returns the first line of b without the line terminator, and the
+// offset of the next line. It reports whether the line terminator was found.
+func cutLine(b []byte) (line []byte, next int, ok bool) {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[:i], i + 1, true
+	}
+	return b, len(b), false
+}
+
+// lookup returns string values stored under the dot-separated key, like
+// “image” or “params.image”. Values may be either strings or lists of
+// strings.
+func (fm *frontMatter) lookup(key string) []string {
+	var v any = fm.values
+	for _, k := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if v, ok = m[k]; !ok {
+			return nil
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// offset returns the byte offset of the first occurrence of s in the front
+// matter in body, or 0 if there's none.
+func (fm *frontMatter) offset(body []byte, s string) int {
+	if i := bytes.Index(body[:fm.end], []byte(s)); i >= 0 {
+		return i
+	}
+	return 0
+}
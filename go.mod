@@ -2,6 +2,15 @@ module github.com/artyom/mdlinks
 
 go 1.18
 
-require github.com/yuin/goldmark v1.4.10
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/yuin/goldmark v1.4.10
+	golang.org/x/net v0.25.0
+	golang.org/x/text v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.20.0 // indirect
 
 retract [v0.3.0, v0.3.1] // Incorrectly handles _ and - when generating header ids.
@@ -0,0 +1,401 @@
+package mdlinks
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// isGoFile reports whether p is a Go source file. Go files matched by
+// Checker.Matcher are checked by links in their doc comments, see
+// extractGoDocDetails.
+func isGoFile(p string) bool { return path.Ext(p) == ".go" }
+
+var (
+	// goLinkDefRe matches doc comment link definitions, like
+	// “[RFC 7230]: https://www.rfc-editor.org/rfc/rfc7230”.
+	goLinkDefRe = regexp.MustCompile(`(?m)^(?://)?[ \t]*\[([^\]\n]+)\]:[ \t]*(\S+)[ \t]*$`)
+
+	// goDocLinkRe matches doc links, like “[Name]”, “[*Name]”, or
+	// “[Type.Method]”.
+	goDocLinkRe = regexp.MustCompile(`\[\*?([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)?)\]`)
+
+	// goMarkdownLinkRe matches markdown-style links, like “[usage](docs/usage.md)”.
+	goMarkdownLinkRe = regexp.MustCompile(`\[[^\]\n]+\]\(([^()\s]+)\)`)
+
+	// goURLRe matches absolute http(s) URLs.
+	goURLRe = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+	// goPathRe matches mentions of files, like “docs/usage.md” or
+	// “./README.md”: paths with an extension that either have a slash or
+	// point to a markdown document.
+	goPathRe = regexp.MustCompile(`(?:\.\.?/)?[\w-][\w.-]*(?:/[\w.-]+)*\.[a-z][a-z0-9]{0,4}`)
+)
+
+// extractGoDocDetails parses Go source file name with the body, and returns
+// links found in its doc comments: targets of link definitions and
+// markdown-style links, mentions of files, like “docs/usage.md”, and absolute
+// URLs; doc links, like “[Name]”, to be checked against declarations of the
+// package; and the package name. Indented lines of comments, which are code
+// blocks, are skipped, and so are doc links, markdown-style links, and file
+// mentions in quotes or code spans, which are usually examples.
+func extractGoDocDetails(name string, body []byte) (*docDetails, []LinkInfo, string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, body, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var groups []*ast.CommentGroup
+	if f.Doc != nil {
+		groups = append(groups, f.Doc)
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		var doc *ast.CommentGroup
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			doc = n.Doc
+		case *ast.GenDecl:
+			doc = n.Doc
+		case *ast.TypeSpec:
+			doc = n.Doc
+		case *ast.ValueSpec:
+			doc = n.Doc
+		case *ast.Field:
+			doc = n.Doc
+		}
+		if doc != nil && doc != f.Doc {
+			groups = append(groups, doc)
+		}
+		return true
+	})
+	d := &docDetails{anchors: make(map[string]struct{})}
+	var refs []LinkInfo // doc links, with the “*” prefix removed
+	addLink := func(raw string, off int) {
+		l := LinkInfo{Raw: raw}
+		l.setOffset(body, off)
+		l.LineStart, l.LineEnd = l.Line, l.Line
+		switch u := localLink(raw); {
+		case isExternalLink(raw):
+			d.external = append(d.external, l)
+		case u != nil:
+			l.Path, l.Fragment = u.Path, u.Fragment
+			d.links = append(d.links, l)
+		default:
+			return
+		}
+		d.all = append(d.all, l)
+	}
+	for _, g := range groups {
+		defined := make(map[string]bool) // texts of link definitions
+		for _, c := range g.List {
+			for _, m := range goLinkDefRe.FindAllStringSubmatch(c.Text, -1) {
+				defined[m[1]] = true
+			}
+		}
+		quoted := goQuotedRanges(body, fset.Position(g.Pos()).Offset, fset.Position(g.End()).Offset)
+		for _, c := range g.List {
+			base := fset.Position(c.Slash).Offset
+			for _, line := range goCommentLines(c.Text) {
+				text := c.Text[line.start:line.end]
+				off := base + line.start
+				if m := goLinkDefRe.FindStringSubmatchIndex(text); m != nil {
+					addLink(text[m[4]:m[5]], off+m[4])
+					continue
+				}
+				var used []int // byte ranges of text already seen as links
+				for _, m := range goURLRe.FindAllStringIndex(text, -1) {
+					m[1] = m[0] + len(strings.TrimRight(text[m[0]:m[1]], ".,;:!?"))
+					addLink(text[m[0]:m[1]], off+m[0])
+					used = append(used, m[0], m[1])
+				}
+				for _, m := range goMarkdownLinkRe.FindAllStringSubmatchIndex(text, -1) {
+					if !inRanges(used, m[2]) && !inRanges(quoted, off+m[0]) {
+						addLink(text[m[2]:m[3]], off+m[2])
+						used = append(used, m[0], m[1])
+					}
+				}
+				for _, m := range goDocLinkRe.FindAllStringSubmatchIndex(text, -1) {
+					name := text[m[2]:m[3]]
+					if inRanges(used, m[0]) || inRanges(quoted, off+m[0]) || defined[name] ||
+						!goLinkBoundary(text, m[0], m[1]) {
+						continue
+					}
+					l := LinkInfo{Raw: name}
+					l.setOffset(body, off+m[2])
+					l.LineStart, l.LineEnd = l.Line, l.Line
+					refs = append(refs, l)
+				}
+				for _, m := range goPathRe.FindAllStringIndex(text, -1) {
+					raw := text[m[0]:m[1]]
+					if inRanges(used, m[0]) || inRanges(quoted, off+m[0]) || !goPathBoundary(text, m[0], m[1]) ||
+						!strings.Contains(raw, "/") && path.Ext(raw) != ".md" {
+						continue
+					}
+					addLink(raw, off+m[0])
+				}
+			}
+		}
+	}
+	for _, links := range [][]LinkInfo{d.links, d.external, d.all} {
+		sort.SliceStable(links, func(i, j int) bool { return links[i].Offset < links[j].Offset })
+	}
+	return d, refs, f.Name.Name, nil
+}
+
+// goCommentLine is a byte range of a comment text line holding prose.
+type goCommentLine struct{ start, end int }
+
+// goCommentLines returns ranges of lines of the comment text, as found in
+// ast.Comment, skipping the comment markers and indented code lines.
+func goCommentLines(text string) []goCommentLine {
+	var out []goCommentLine
+	start, end := 2, len(text) // skip “//” or “/*”
+	if strings.HasPrefix(text, "/*") {
+		end -= 2
+	}
+	for i := start; i <= end; {
+		j := strings.IndexByte(text[i:end], '\n')
+		if j < 0 {
+			j = end - i
+		}
+		line := text[i : i+j]
+		// prose lines have at most one space after the comment marker
+		trimmed := strings.TrimPrefix(line, " ")
+		if !strings.HasPrefix(trimmed, " ") && !strings.HasPrefix(trimmed, "\t") {
+			out = append(out, goCommentLine{start: i + len(line) - len(trimmed), end: i + j})
+		}
+		i += j + 1
+	}
+	return out
+}
+
+// inRanges reports whether offset i is inside one of ranges, given as pairs
+// of start and end offsets.
+func inRanges(ranges []int, i int) bool {
+	for j := 0; j+1 < len(ranges); j += 2 {
+		if i >= ranges[j] && i < ranges[j+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// goLinkBoundary reports whether text[start:end] is delimited the way Go doc
+// links are: preceded and followed by a space, punctuation, or a line
+// boundary, but not by letters, digits, or brackets.
+func goLinkBoundary(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start != 0 && (isWordRune(r) || r == '[' || r == ']') {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end != len(text) && (isWordRune(r) || r == '[' || r == '(' || r == ':') {
+		return false
+	}
+	return true
+}
+
+// goQuotedRanges returns byte ranges of body[start:end], a comment group, in
+// typographic quotes, like “docs/usage.md”, which may span lines, and in
+// ASCII double quotes or backticks, which are paired within a line only, as
+// pairs of start and end offsets in body.
+func goQuotedRanges(body []byte, start, end int) []int {
+	var out []int
+	open := -1 // offset of the opening typographic quote
+	pending := make(map[rune]int)
+	for i, r := range string(body[start:end]) {
+		i += start
+		switch r {
+		case '“':
+			if open < 0 {
+				open = i
+			}
+		case '”':
+			if open >= 0 {
+				out = append(out, open, i+len("”"))
+				open = -1
+			}
+		case '"', '`':
+			if j, ok := pending[r]; ok {
+				out = append(out, j, i+1)
+				delete(pending, r)
+			} else {
+				pending[r] = i
+			}
+		case '\n':
+			pending = make(map[rune]int)
+		}
+	}
+	return out
+}
+
+// goPathBoundary reports whether text[start:end] is a whole word, not a part
+// of a longer path, URL, or identifier. Paths following quote marks are
+// skipped too, see goQuotedRanges for the ones inside longer quotes.
+func goPathBoundary(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start != 0 && !unicode.IsSpace(r) && r != '(' && r != '<' {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end != len(text) && (isWordRune(r) || strings.ContainsRune("/.-", r) && end+1 < len(text) && !unicode.IsSpace(rune(text[end+1]))) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+// parseGoFile extracts details of the Go source file p with the body b,
+// reporting doc links to identifiers not declared in its package, and
+// resolving file mentions not found relative to p against the root of fsys.
+func (st *checkState) parseGoFile(p string, b []byte) (*docDetails, error) {
+	d, refs, pkg, err := extractGoDocDetails(p, b)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(p)
+	for _, links := range [][]LinkInfo{d.links, d.all} {
+		for i, l := range links {
+			if l.Path == "" || strings.HasPrefix(l.Path, "/") || st.exists(path.Join(dir, l.Path)) {
+				continue
+			}
+			if rootPath := path.Clean(l.Path); fs.ValidPath(rootPath) && st.exists(rootPath) {
+				links[i].Path = "/" + rootPath // relative to the module root
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return d, nil
+	}
+	decls, err := st.goDecls(dir, pkg)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range refs {
+		name, _, dotted := strings.Cut(l.Raw, ".")
+		switch {
+		case decls[l.Raw] || !dotted && goPredeclared[name]:
+		case dotted && !decls[name]: // likely [pkg.Name], which isn't resolved
+		case !dotted && !unicode.IsUpper(rune(name[0])):
+			// [word] in prose is more likely not a doc link
+		default:
+			d.undeclared = append(d.undeclared, l)
+		}
+	}
+	return d, nil
+}
+
+// goDecls returns names of top-level declarations of the package pkg in the
+// directory dir, along with methods and struct fields of its types, as
+// “Type.Name”.
+func (st *checkState) goDecls(dir, pkg string) (map[string]bool, error) {
+	key := dir + "\x00" + pkg
+	st.mu.Lock()
+	decls, ok := st.goDeclIndex[key]
+	st.mu.Unlock()
+	if ok {
+		return decls, nil
+	}
+	entries, err := fs.ReadDir(st.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	decls = make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		if e.IsDir() || !isGoFile(e.Name()) {
+			continue
+		}
+		b, err := fs.ReadFile(st.fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, e.Name(), b, parser.SkipObjectResolution)
+		if err != nil || f.Name.Name != pkg {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) == 0 {
+					decls[decl.Name.Name] = true
+					continue
+				}
+				if recv := receiverName(decl.Recv.List[0].Type); recv != "" {
+					decls[recv+"."+decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						decls[spec.Name.Name] = true
+						var fields *ast.FieldList
+						switch t := spec.Type.(type) {
+						case *ast.StructType:
+							fields = t.Fields
+						case *ast.InterfaceType:
+							fields = t.Methods
+						}
+						if fields == nil {
+							continue
+						}
+						for _, field := range fields.List {
+							for _, n := range field.Names {
+								decls[spec.Name.Name+"."+n.Name] = true
+							}
+						}
+					case *ast.ValueSpec:
+						for _, n := range spec.Names {
+							decls[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.goDeclIndex == nil {
+		st.goDeclIndex = make(map[string]map[string]bool)
+	}
+	st.goDeclIndex[key] = decls
+	return decls, nil
+}
+
+// receiverName returns the name of the method receiver type, like “T” for
+// “*T” or “T[K]”.
+func receiverName(x ast.Expr) string {
+	for {
+		switch t := x.(type) {
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.IndexListExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// goPredeclared are the predeclared identifiers, which doc links refer to
+// the builtin package.
+var goPredeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true, "min": true,
+	"new": true, "panic": true, "print": true, "println": true, "real": true, "recover": true,
+}
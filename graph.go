@@ -0,0 +1,102 @@
+package mdlinks
+
+import (
+	"io/fs"
+	"path"
+)
+
+// Graph describes links between local files.
+type Graph struct {
+	Files []string // documents matched by Checker.Matcher, in traversal order
+	Edges []Edge
+}
+
+// Edge is a link from a document to an existing local file.
+type Edge struct {
+	From string // document path; uses '/' as a separator
+	To   string // link target path; uses '/' as a separator
+	Link LinkInfo
+}
+
+// Graph walks file system fsys the same way CheckFS does, and returns links
+// between documents and other local files found. Links to non-existing files,
+// external links, and links to anchors of the same document are not included.
+// Links to directories point to their DirectoryIndex documents, if any.
+func (c *Checker) Graph(fsys fs.FS) (*Graph, error) {
+	if c == nil {
+		panic("mdlinks: Graph called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: Graph called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	g := new(Graph)
+	fn := func(p string) error {
+		g.Files = append(g.Files, p)
+		edges, err := st.edges(p)
+		if err != nil {
+			return err
+		}
+		g.Edges = append(g.Edges, edges...)
+		return nil
+	}
+	if err := c.walk(fsys, fn); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// edges returns links of the document p to existing local files.
+func (st *checkState) edges(p string) ([]Edge, error) {
+	docMeta, err := st.fileMeta(p)
+	if err != nil {
+		return nil, err
+	}
+	var out []Edge
+	for _, s := range docMeta.links {
+		if s.Path == "" || st.ignored(s.Raw) {
+			continue
+		}
+		target, err := st.resolve(p, s)
+		if err != nil {
+			return nil, err
+		}
+		if !st.exists(target) {
+			continue
+		}
+		out = append(out, Edge{From: p, To: st.indexDocument(target), Link: s})
+	}
+	return out, nil
+}
+
+// Backlinks returns the index of links to local files found in documents of
+// fsys, keyed by the link target path. For each target, links are in the
+// traversal order. See Graph for the details on which links are included.
+func (c *Checker) Backlinks(fsys fs.FS) (map[string][]Edge, error) {
+	g, err := c.Graph(fsys)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string][]Edge)
+	for _, e := range g.Edges {
+		index[e.To] = append(index[e.To], e)
+	}
+	return index, nil
+}
+
+// Backlinks walks file system fsys looking for files with their base names
+// matching pattern pat (e.g. “*.md”), and returns the index of links to local
+// files found in them, keyed by the link target path. See Checker.Backlinks
+// for details.
+func Backlinks(fsys fs.FS, pat string) (map[string][]Edge, error) {
+	if _, err := path.Match(pat, "xxx"); err != nil { // report bad pattern early
+		return nil, err
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Match(pat, path.Base(s)) },
+	}
+	return c.Backlinks(fsys)
+}
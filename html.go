@@ -0,0 +1,99 @@
+package mdlinks
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"golang.org/x/net/html"
+)
+
+// rawHTML returns raw html source of the html block or inline html node.
+func rawHTML(n ast.Node, src []byte) []byte {
+	var b bytes.Buffer
+	switch n := n.(type) {
+	case *ast.HTMLBlock:
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			b.Write(seg.Value(src))
+		}
+		if n.HasClosure() {
+			b.Write(n.ClosureLine.Value(src))
+		}
+	case *ast.RawHTML:
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			b.Write(seg.Value(src))
+		}
+	}
+	return b.Bytes()
+}
+
+// htmlAttrs tokenizes html fragment and calls fn for each attribute of each
+// start (or self-closing) tag.
+func htmlAttrs(src []byte, fn func(tag, key, val string)) {
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				fn(tag, string(k), strings.TrimSpace(string(v)))
+			}
+		}
+	}
+}
+
+// htmlAnchors returns values of id attributes and name attributes of <a>
+// elements found in html fragment src.
+func htmlAnchors(src []byte) []string {
+	var out []string
+	htmlAttrs(src, func(tag, key, val string) {
+		if val == "" {
+			return
+		}
+		if key == "id" || (key == "name" && tag == "a") {
+			out = append(out, val)
+		}
+	})
+	return out
+}
+
+// htmlLinks returns link targets from href and src attributes, and urls from
+// srcset attributes found in html fragment src.
+func htmlLinks(src []byte) []string {
+	var out []string
+	htmlAttrs(src, func(tag, key, val string) {
+		if val == "" {
+			return
+		}
+		switch key {
+		case "href", "src":
+			out = append(out, val)
+		case "srcset":
+			// comma-separated list of “url [descriptor]” candidates
+			for _, cand := range strings.Split(val, ",") {
+				if f := strings.Fields(cand); len(f) != 0 {
+					out = append(out, f[0])
+				}
+			}
+		}
+	})
+	return out
+}
+
+// isHTMLFile reports whether /-separated path p names an html document.
+func isHTMLFile(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".html", ".htm", ".xhtml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package mdlinks
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// hugoRefRe matches Hugo “{{< ref "page.md#anchor" >}}” and “{{% relref
+// "page" %}}” shortcodes, capturing their arguments.
+var hugoRefRe = regexp.MustCompile(`\{\{[<%]-?\s*(?:ref|relref)\s+(.*?)\s*-?[>%]\}\}`)
+
+// hugoArgRe matches a single, possibly named, shortcode argument.
+var hugoArgRe = regexp.MustCompile("(?:(\\w+)=)?(?:\"([^\"]*)\"|`([^`]*)`)")
+
+// extractHugoRefs returns links from ref and relref shortcodes in body.
+func extractHugoRefs(body []byte) []LinkInfo {
+	var out []LinkInfo
+	for _, m := range hugoRefRe.FindAllSubmatchIndex(body, -1) {
+		var target string
+		var named bool
+		for _, a := range hugoArgRe.FindAllSubmatch(body[m[2]:m[3]], -1) {
+			val := string(a[2]) + string(a[3])
+			switch name := string(a[1]); {
+			case name == "path":
+				target, named = val, true
+			case name == "" && !named && target == "":
+				target = val
+			}
+		}
+		if target == "" {
+			continue
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme != "" || u.Host != "" {
+			continue
+		}
+		line := 1 + bytes.Count(body[:m[0]], []byte{'\n'})
+		l := LinkInfo{
+			Raw:       string(body[m[0]:m[1]]),
+			Path:      u.Path,
+			Fragment:  u.Fragment,
+			LineStart: line,
+			LineEnd:   line + bytes.Count(body[m[0]:m[1]], []byte{'\n'}),
+		}
+		l.setOffset(body, m[0])
+		out = append(out, l)
+	}
+	return out
+}
+
+// hugoCandidates returns file names a Hugo page reference may point to: page
+// references without an extension may refer to a “page.md” file, or to a
+// leaf or a branch bundle (“page/index.md” or “page/_index.md”).
+func hugoCandidates(name string) []string {
+	if path.Ext(name) != "" {
+		return []string{name}
+	}
+	return []string{
+		name + ".md",
+		path.Join(name, "index.md"),
+		path.Join(name, "_index.md"),
+		name,
+	}
+}
+
+// resolveHugoRef resolves Hugo page reference ref found in the document p,
+// using Hugo rules: references starting with a slash are relative to the
+// content root, others are first looked up relative to the page, then
+// relative to the content root. It returns an empty string if ref cannot be
+// resolved.
+func (st *checkState) resolveHugoRef(p, ref string) string {
+	var dirs []string
+	if strings.HasPrefix(ref, "/") {
+		dirs = []string{"."}
+	} else {
+		dirs = []string{path.Dir(p), "."}
+	}
+	for _, dir := range dirs {
+		for _, name := range hugoCandidates(ref) {
+			cand := strings.TrimPrefix(path.Join(dir, name), "/")
+			if st.exists(cand) {
+				return cand
+			}
+		}
+	}
+	return ""
+}
+
+// checkHugoRefs checks links from ref and relref shortcodes of the document p.
+func (st *checkState) checkHugoRefs(p string, docMeta *docDetails) ([]BrokenLink, error) {
+	var out []BrokenLink
+	for _, s := range docMeta.hugo {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		meta, anchorsOf := docMeta, p
+		if s.Path != "" {
+			target := st.resolveHugoRef(p, s.Path)
+			if target == "" {
+				out = append(out, BrokenLink{File: p, Link: s})
+				continue
+			}
+			if s.Fragment == "" {
+				continue
+			}
+			var err error
+			if meta, err = st.targetMeta(target); err != nil {
+				return nil, err
+			}
+			anchorsOf = st.indexDocument(target)
+		}
+		if meta == nil || s.Fragment == "" {
+			continue
+		}
+		if _, ok := meta.anchors[s.Fragment]; ok {
+			continue
+		}
+		switch valid, err := st.fragmentValid(anchorsOf, s.Fragment); {
+		case err != nil:
+			return nil, err
+		case valid:
+			continue
+		}
+		kind := kindBrokenExternalAnchor
+		if s.Path == "" {
+			kind = kindBrokenInternalAnchor
+		}
+		out = append(out, BrokenLink{File: p, Link: s, kind: kind})
+	}
+	return out, nil
+}
@@ -0,0 +1,244 @@
+package mdlinks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ExcludePatterns returns a function suitable to be used as Checker.Exclude
+// that matches paths against gitignore-style patterns, as found in
+// .gitignore files: patterns without a slash match names at any depth,
+// patterns with a slash are relative to the filesystem root, a trailing slash
+// only matches directories, “**” matches any number of directories, and a
+// leading “!” negates the pattern. Empty lines and lines starting with “#”
+// are ignored. The last matching pattern decides the outcome.
+func ExcludePatterns(patterns []string) (func(path string, isDir bool) (bool, error), error) {
+	rules, err := parseIgnoreRules("", patterns)
+	if err != nil {
+		return nil, err
+	}
+	return func(p string, isDir bool) (bool, error) {
+		excluded, _ := rules.match(p, isDir)
+		return excluded, nil
+	}, nil
+}
+
+// MatchPatterns returns a function suitable to be used as Checker.Matcher
+// that matches paths against any of glob patterns: patterns without a slash,
+// like “*.md”, match base names at any depth, patterns with a slash, like
+// “docs/**/*.md”, match paths relative to the filesystem root, and “**”
+// matches any number of directories.
+func MatchPatterns(patterns []string) (func(path string) (bool, error), error) {
+	var res []*regexp.Regexp
+	for _, pat := range patterns {
+		anchored := strings.Contains(pat, "/")
+		expr, err := globRegexp(strings.TrimPrefix(pat, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("mdlinks: bad pattern %q: %w", pat, err)
+		}
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "(?:^|/)" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("mdlinks: bad pattern %q: %w", pat, err)
+		}
+		res = append(res, re)
+	}
+	return func(p string) (bool, error) {
+		for _, re := range res {
+			if re.MatchString(p) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+// ignoreRules is a set of gitignore-style rules defined in the base directory
+type ignoreRules struct {
+	base  string // /-separated directory path rules are relative to, "" for root
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func parseIgnoreRules(base string, lines []string) (*ignoreRules, error) {
+	out := &ignoreRules{base: base}
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line[:len(line)-2], " ") + `\ `
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var r ignoreRule
+		if line[0] == '!' {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr, err := globRegexp(line)
+		if err != nil {
+			return nil, fmt.Errorf("mdlinks: bad ignore pattern %q: %w", line, err)
+		}
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "(?:^|/)" + expr + "$"
+		}
+		if r.re, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("mdlinks: bad ignore pattern %q: %w", line, err)
+		}
+		out.rules = append(out.rules, r)
+	}
+	return out, nil
+}
+
+// match reports whether /-separated path p (relative to the filesystem root)
+// is excluded by rules, and whether any rule matched at all.
+func (ir *ignoreRules) match(p string, isDir bool) (excluded, matched bool) {
+	if ir.base != "" {
+		if !strings.HasPrefix(p, ir.base+"/") {
+			return false, false
+		}
+		p = p[len(ir.base)+1:]
+	}
+	for _, r := range ir.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(p) {
+			excluded, matched = !r.negate, true
+		}
+	}
+	return excluded, matched
+}
+
+// globRegexp converts glob pattern with “**” support into a regular
+// expression, without anchors.
+func globRegexp(pat string) (string, error) {
+	if _, err := path.Match(strings.ReplaceAll(pat, "**", "*"), ""); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i := 0; i < len(pat); i++ {
+		switch c := pat[i]; c {
+		case '*':
+			if strings.HasPrefix(pat[i:], "**") {
+				atStart := i == 0 || pat[i-1] == '/'
+				rest := pat[i+2:]
+				switch {
+				case atStart && strings.HasPrefix(rest, "/"): // “**/”
+					b.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				case atStart && rest == "": // trailing “/**”
+					b.WriteString(".*")
+					i++
+					continue
+				}
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := strings.IndexByte(pat[i+1:], ']')
+			if j < 0 {
+				return "", path.ErrBadPattern
+			}
+			class := pat[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += j + 1
+		case '\\':
+			if i+1 < len(pat) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pat[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// gitignoreSet tracks rules from .gitignore files found while traversing
+// a filesystem
+type gitignoreSet struct {
+	fsys  fs.FS
+	byDir map[string]*ignoreRules // keys are directory paths, "" for root
+}
+
+// load reads .gitignore file from the directory dir, if it exists.
+func (g *gitignoreSet) load(dir string) error {
+	if dir == "." {
+		dir = ""
+	}
+	b, err := fs.ReadFile(g.fsys, path.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rules, err := parseIgnoreRules(dir, strings.Split(string(b), "\n"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path.Join(dir, ".gitignore"), err)
+	}
+	if g.byDir == nil {
+		g.byDir = make(map[string]*ignoreRules)
+	}
+	g.byDir[dir] = rules
+	return nil
+}
+
+// excluded reports whether p is ignored by rules loaded from .gitignore files
+// in its ancestor directories; rules from deeper directories take precedence.
+func (g *gitignoreSet) excluded(p string, isDir bool) bool {
+	var out bool
+	dir := ""
+	for {
+		if rules, ok := g.byDir[dir]; ok {
+			if excluded, matched := rules.match(p, isDir); matched {
+				out = excluded
+			}
+		}
+		rest := strings.TrimPrefix(p, dir)
+		rest = strings.TrimPrefix(rest, "/")
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			return out
+		}
+		if dir == "" {
+			dir = rest[:i]
+		} else {
+			dir = dir + "/" + rest[:i]
+		}
+	}
+}
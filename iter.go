@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package mdlinks
+
+import (
+	"io/fs"
+	"iter"
+)
+
+// Broken runs the same checks as CheckFS, but yields broken links as soon as
+// they're found, instead of collecting them into a *BrokenLinksError. Links
+// to unreachable external resources are only yielded after the links of all
+// matched files. If the check fails, the last pair yielded has a non-nil
+// error. Breaking out of the loop stops the check:
+//
+//	for l, err := range checker.Broken(fsys) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(l)
+//	}
+func (c *Checker) Broken(fsys fs.FS) iter.Seq2[BrokenLink, error] {
+	if c == nil {
+		panic("mdlinks: Broken called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: Broken called with a nil Checker.Matcher")
+	}
+	return func(yield func(BrokenLink, error) bool) {
+		var stopped bool
+		err := c.stream(fsys, func(l BrokenLink) bool {
+			stopped = !yield(l, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(BrokenLink{}, err)
+		}
+	}
+}
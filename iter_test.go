@@ -0,0 +1,41 @@
+//go:build go1.23
+
+package mdlinks
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestChecker_Broken(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("[x](missing.md)\n\n[y](#nope)\n")},
+		"b.md": &fstest.MapFile{Data: []byte("[z](gone.md)\n")},
+	}
+	var files []string
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		OnFile:  func(p string) { files = append(files, p) },
+	}
+	var got []string
+	for l, err := range c.Broken(fsys) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, l.File+" "+l.Link.Raw)
+	}
+	if want := "a.md missing.md|a.md #nope|b.md gone.md"; strings.Join(got, "|") != want {
+		t.Fatalf("got %q, want %q", strings.Join(got, "|"), want)
+	}
+	files, got = nil, nil
+	for l := range c.Broken(fsys) {
+		got = append(got, l.Link.Raw)
+		break
+	}
+	if len(got) != 1 || len(files) != 0 {
+		t.Fatalf("after break, got links %q and checked files %q, want a single link and no files", got, files)
+	}
+}
@@ -0,0 +1,98 @@
+package mdlinks
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// jekyllTagRe matches Liquid “{% link path/to/file.md %}” and “{% post_url
+// 2023-01-01-slug %}” tags, capturing the tag name and its argument.
+var jekyllTagRe = regexp.MustCompile(`\{%-?\s*(link|post_url)\s+(\S+?)\s*-?%\}`)
+
+// jekyllTag is a link from the Jekyll link or post_url tag.
+type jekyllTag struct {
+	LinkInfo
+	post bool // post_url tag
+}
+
+// extractJekyllTags returns links from link and post_url tags in body.
+func extractJekyllTags(body []byte) []jekyllTag {
+	var out []jekyllTag
+	for _, m := range jekyllTagRe.FindAllSubmatchIndex(body, -1) {
+		arg := string(body[m[4]:m[5]])
+		if strings.Contains(arg, "{{") { // liquid variable, can't be resolved
+			continue
+		}
+		line := 1 + bytes.Count(body[:m[0]], []byte{'\n'})
+		tag := jekyllTag{
+			LinkInfo: LinkInfo{
+				Raw:       string(body[m[0]:m[1]]),
+				Path:      arg,
+				LineStart: line,
+				LineEnd:   line,
+			},
+			post: string(body[m[2]:m[3]]) == "post_url",
+		}
+		tag.setOffset(body, m[0])
+		out = append(out, tag)
+	}
+	return out
+}
+
+// postExts are the extensions of Jekyll post sources.
+var postExts = []string{".md", ".markdown", ".html"}
+
+// resolveJekyllPost returns path of the post source file for the post_url tag
+// argument name, like “2023-01-01-slug” or “subdir/2023-01-01-slug”, or an
+// empty string if there is no such post. Posts are looked up in all “_posts”
+// directories.
+func (st *checkState) resolveJekyllPost(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	for _, ext := range postExts {
+		cands, err := st.filesNamed(path.Base(name) + ext)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range cands {
+			dir := path.Dir(c)
+			if sub := path.Dir(name); sub != "." {
+				if !strings.HasSuffix(dir, "/"+sub) {
+					continue
+				}
+				dir = strings.TrimSuffix(dir, "/"+sub)
+			}
+			if path.Base(dir) == "_posts" {
+				return c, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// checkJekyllTags checks links from link and post_url tags of the document p.
+// Paths of link tags are relative to the site source root, which is the root
+// of the filesystem.
+func (st *checkState) checkJekyllTags(p string, docMeta *docDetails) ([]BrokenLink, error) {
+	var out []BrokenLink
+	for _, s := range docMeta.jekyll {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		var ok bool
+		if s.post {
+			target, err := st.resolveJekyllPost(s.Path)
+			if err != nil {
+				return nil, err
+			}
+			ok = target != ""
+		} else {
+			ok = st.exists(strings.TrimPrefix(path.Clean(s.Path), "/"))
+		}
+		if !ok {
+			out = append(out, BrokenLink{File: p, Link: s.LinkInfo})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package mdlinks
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"regexp"
+	"strconv"
+)
+
+// lineFragmentRe matches GitHub line fragments, like “L10”, “L10-L20”, or
+// “L10C5-L20C3”.
+var lineFragmentRe = regexp.MustCompile(`^L(\d+)(?:C\d+)?(?:-L(\d+)(?:C\d+)?)?$`)
+
+// lineFragment returns the first and the last line of the line fragment, like
+// “L10-L20”; for single line fragments both are the same. It returns false if
+// fragment is not a line fragment.
+func lineFragment(fragment string) (from, to int, ok bool) {
+	m := lineFragmentRe.FindStringSubmatch(fragment)
+	if m == nil {
+		return 0, 0, false
+	}
+	from, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	to = from
+	if m[2] != "" {
+		if to, err = strconv.Atoi(m[2]); err != nil {
+			return 0, 0, false
+		}
+	}
+	return from, to, true
+}
+
+// linesInRange reports whether the file p has lines from first to last. It
+// returns true for targets it can't read, like directories.
+func (st *checkState) linesInRange(p string, first, last int) (bool, error) {
+	st.mu.Lock()
+	n, ok := st.lineCounts[p]
+	st.mu.Unlock()
+	if !ok {
+		if p == st.virtual {
+			return true, nil
+		}
+		switch fi, err := fs.Stat(st.fsys, p); {
+		case errors.Is(err, fs.ErrNotExist):
+			return true, nil // exists only according to TargetExists
+		case err != nil:
+			return false, err
+		case fi.IsDir():
+			return true, nil
+		}
+		b, err := fs.ReadFile(st.fsys, p)
+		if err != nil {
+			return false, err
+		}
+		n = bytes.Count(b, []byte{'\n'})
+		if len(b) != 0 && b[len(b)-1] != '\n' {
+			n++
+		}
+		st.mu.Lock()
+		if st.lineCounts == nil {
+			st.lineCounts = make(map[string]int)
+		}
+		st.lineCounts[p] = n
+		st.mu.Unlock()
+	}
+	return first >= 1 && first <= last && last <= n, nil
+}
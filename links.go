@@ -0,0 +1,125 @@
+package mdlinks
+
+import (
+	"io/fs"
+	"sort"
+)
+
+// FileLinks holds links found in a single document, see Checker.Links.
+type FileLinks struct {
+	File  string // document path; uses '/' as a separator
+	Links []ResolvedLink
+}
+
+// ResolvedLink is a link along with the result of its resolution.
+type ResolvedLink struct {
+	LinkInfo
+
+	// External is true for absolute http(s) links, which are not resolved.
+	External bool
+
+	// Target is the path of the file or directory the local link points to,
+	// after applying the optional resolution rules, like InferExtensions;
+	// for links without a path, like “#fragment”, it's the document itself.
+	// It's empty for external links.
+	Target string
+
+	// Exists reports whether Target exists.
+	Exists bool
+}
+
+// Links walks file system fsys the same way CheckFS does, and returns local
+// and external links of every matched document, in the document order.
+// Documents are in the traversal order; documents without links are
+// included too. Links matching IgnoreLinks are skipped.
+func (c *Checker) Links(fsys fs.FS) ([]FileLinks, error) {
+	if c == nil {
+		panic("mdlinks: Links called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: Links called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	var out []FileLinks
+	fn := func(p string) error {
+		fl, err := st.fileLinks(p)
+		if err != nil {
+			return err
+		}
+		out = append(out, fl)
+		return nil
+	}
+	if err := c.walk(fsys, fn); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DocumentLinks returns links of the single document name of fsys, the way
+// Links does. The document is parsed regardless of Matcher.
+func (c *Checker) DocumentLinks(fsys fs.FS, name string) (FileLinks, error) {
+	if c == nil {
+		panic("mdlinks: DocumentLinks called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: DocumentLinks called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return FileLinks{}, err
+	}
+	return st.fileLinks(name)
+}
+
+// fileLinks returns links of the document p, see Checker.Links.
+func (st *checkState) fileLinks(p string) (FileLinks, error) {
+	docMeta, err := st.fileMeta(p)
+	if err != nil {
+		return FileLinks{}, err
+	}
+	fl := FileLinks{File: p}
+	for _, s := range docMeta.links {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		target, err := st.resolve(p, s)
+		if err != nil {
+			return FileLinks{}, err
+		}
+		if target == "" {
+			target = p
+		}
+		fl.Links = append(fl.Links, ResolvedLink{LinkInfo: s, Target: target, Exists: st.exists(target)})
+	}
+	for _, s := range docMeta.external {
+		if !st.ignored(s.Raw) {
+			fl.Links = append(fl.Links, ResolvedLink{LinkInfo: s, External: true})
+		}
+	}
+	sort.SliceStable(fl.Links, func(i, j int) bool { return linkBefore(fl.Links[i].LinkInfo, fl.Links[j].LinkInfo) })
+	return fl, nil
+}
+
+// ResolveLink returns the path that the link destination dest, found in the
+// document fromFile, points to, along with its decoded fragment. Both paths
+// are relative to the root of the checked file system and use '/' as a
+// separator; absolute links, like “/docs/setup.md”, are resolved against
+// the root. Links without a path, like “#fragment”, point to fromFile. For
+// external links, like “https://example.org/”, both results are empty.
+//
+// The target is resolved literally, as CheckFS does before trying optional
+// resolution rules, like Checker.InferExtensions. Links escaping the root
+// result in paths starting with “../”.
+func ResolveLink(fromFile, dest string) (target, fragment string) {
+	u := localLink(dest)
+	if u == nil {
+		return "", ""
+	}
+	if target = linkPath(fromFile, LinkInfo{Path: u.Path}); target == "" {
+		target = fromFile
+	}
+	return target, u.Fragment
+}
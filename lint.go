@@ -0,0 +1,107 @@
+package mdlinks
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// isPlaceholderLink reports whether raw link is an obvious placeholder, like
+// “#”, “TODO”, or “http://example.com”. Fragments, like “#todo”, are not
+// placeholders, as they may point to headers like “TODO”.
+func isPlaceholderLink(raw string) bool {
+	switch strings.ToLower(raw) {
+	case "", "#", "todo", "tbd", "fixme", "xxx", "link", "url":
+		return true
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	switch strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") {
+	case "example.com", "example.org", "example.net":
+		return true
+	}
+	return false
+}
+
+// lintPath checks the link s from the document p pointing to the existing
+// fsys path target, and reports whether it's a link to the document itself
+// without a fragment, or has redundant path segments.
+func (st *checkState) lintPath(p string, s LinkInfo, target string) (BrokenLink, bool) {
+	if target == p && s.Fragment == "" {
+		return BrokenLink{File: p, Link: s, kind: kindSelfLink}, true
+	}
+	if linkPath(p, s) != target { // resolved with extra rules, like inferred extension
+		return BrokenLink{}, false
+	}
+	dir := path.Dir(p)
+	if target == "." || target == dir || strings.HasPrefix(dir, target+"/") {
+		return BrokenLink{}, false // links to ancestor directories, like “../”
+	}
+	var canonical string
+	if strings.HasPrefix(s.Path, "/") {
+		canonical = "/" + target
+	} else {
+		canonical = relativePath(dir, target)
+		if strings.HasPrefix(s.Path, "./") && !strings.HasPrefix(canonical, "../") {
+			canonical = "./" + canonical
+		}
+	}
+	if strings.HasSuffix(s.Path, "/") {
+		canonical += "/"
+	}
+	if canonical == s.Path {
+		return BrokenLink{}, false
+	}
+	return BrokenLink{
+		File:       p,
+		Link:       s,
+		Suggestion: st.replaceLinkPath(s.Raw, p, target),
+		kind:       kindRedundantPath,
+	}, true
+}
+
+// LinkStyle selects the form of links to local files enforced by
+// Checker.LinkStyle.
+type LinkStyle byte
+
+const (
+	// LinkStyleAny accepts both relative and root-absolute links.
+	LinkStyleAny LinkStyle = iota
+
+	// LinkStyleRelative expects links relative to the linking document,
+	// like “../guide.md”.
+	LinkStyleRelative
+
+	// LinkStyleAbsolute expects links relative to the root of fsys, like
+	// “/docs/guide.md”.
+	LinkStyleAbsolute
+)
+
+// lintStyle checks that the link s from the document p has the form selected
+// by Checker.LinkStyle, and reports whether it doesn't, suggesting the link
+// of the right form.
+func (st *checkState) lintStyle(p string, s LinkInfo) (BrokenLink, bool) {
+	target := linkPath(p, s)
+	if target == "" || target == "." || strings.HasPrefix(target, "../") {
+		return BrokenLink{}, false
+	}
+	switch {
+	case st.c.LinkStyle == LinkStyleRelative && strings.HasPrefix(s.Raw, "/"):
+		return BrokenLink{
+			File:       p,
+			Link:       s,
+			Suggestion: replaceLinkPath(s.Raw[1:], p, target),
+			kind:       kindAbsoluteLink,
+		}, true
+	case st.c.LinkStyle == LinkStyleAbsolute && !strings.HasPrefix(s.Raw, "/") && !strings.HasPrefix(s.Path, "/"):
+		return BrokenLink{
+			File:       p,
+			Link:       s,
+			Suggestion: replaceLinkPath("/"+s.Raw, p, target),
+			kind:       kindRelativeLink,
+		}, true
+	}
+	return BrokenLink{}, false
+}
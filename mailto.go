@@ -0,0 +1,56 @@
+package mdlinks
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// isMailtoLink reports whether raw is a “mailto:” link.
+func isMailtoLink(raw string) bool {
+	return len(raw) >= len("mailto:") && strings.EqualFold(raw[:len("mailto:")], "mailto:")
+}
+
+// mailtoProblem checks the “mailto:” link raw, like
+// “mailto:team@example.com?cc=lead@example.com”, and returns the kind of its
+// problem, if any: no addresses at all, an address not valid per RFC 5322,
+// or the same address listed more than once.
+func mailtoProblem(raw string) (violationKind, bool) {
+	to, query, _ := strings.Cut(raw[len("mailto:"):], "?")
+	to, err := url.PathUnescape(to)
+	if err != nil {
+		return kindInvalidEmail, true
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return kindInvalidEmail, true
+	}
+	lists := []string{to}
+	for k, v := range values {
+		switch strings.ToLower(k) {
+		case "to", "cc", "bcc":
+			lists = append(lists, v...)
+		}
+	}
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		if list == "" {
+			continue
+		}
+		for _, s := range strings.Split(list, ",") {
+			addr, err := mail.ParseAddress(s)
+			if err != nil {
+				return kindInvalidEmail, true
+			}
+			key := strings.ToLower(addr.Address)
+			if _, ok := seen[key]; ok {
+				return kindDuplicateEmail, true
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return kindEmptyMailto, true
+	}
+	return 0, false
+}
@@ -0,0 +1,34 @@
+package mdlinks
+
+import (
+	"path"
+	"strings"
+)
+
+// checkMdBookUnlisted reports documents in the mdBook source directory (the
+// directory of the summary file) that are not referenced from the summary;
+// checked are the files passed to Matcher during traversal.
+func (st *checkState) checkMdBookUnlisted(summary string, checked []string) ([]BrokenLink, error) {
+	meta, err := st.fileMeta(summary)
+	if err != nil {
+		return nil, err
+	}
+	srcDir := path.Dir(summary)
+	listed := make(map[string]struct{})
+	for _, l := range meta.links {
+		if l.Path == "" || strings.HasPrefix(l.Path, "/") {
+			continue
+		}
+		listed[path.Join(srcDir, l.Path)] = struct{}{}
+	}
+	var out []BrokenLink
+	for _, p := range checked {
+		if p == summary || (srcDir != "." && !strings.HasPrefix(p, srcDir+"/")) {
+			continue
+		}
+		if _, ok := listed[p]; !ok {
+			out = append(out, BrokenLink{File: p, kind: kindNotInSummary})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package mdlinks
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mkdocsNavEntry is a page referenced from the MkDocs nav.
+type mkdocsNavEntry struct {
+	target string // as written in the config
+	line   int
+	column int // in bytes
+	offset int
+}
+
+// parseMkDocsConfig parses MkDocs configuration file, returning its docs_dir
+// setting and all local pages referenced from its nav.
+func parseMkDocsConfig(b []byte) (docsDir string, entries []mkdocsNavEntry, err error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return "", nil, err
+	}
+	docsDir = "docs"
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return docsDir, nil, nil
+	}
+	root := doc.Content[0]
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if n.Value != "" && !isExternalLink(n.Value) && !strings.Contains(n.Value, "://") {
+				e := mkdocsNavEntry{target: n.Value, line: n.Line}
+				e.offset, e.column = yamlOffset(b, n.Line, n.Column)
+				entries = append(entries, e)
+			}
+		case yaml.SequenceNode:
+			for _, item := range n.Content {
+				walk(item)
+			}
+		case yaml.MappingNode: // “title: page.md” or “section: [...]”
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "docs_dir":
+			docsDir = root.Content[i+1].Value
+		case "nav":
+			walk(root.Content[i+1])
+		}
+	}
+	return docsDir, entries, nil
+}
+
+// yamlOffset converts 1-based line and column, in characters, of a yaml
+// node to the byte offset in b and the column in bytes.
+func yamlOffset(b []byte, line, column int) (offset, byteColumn int) {
+	for i := 1; i < line; i++ {
+		j := bytes.IndexByte(b[offset:], '\n')
+		if j < 0 {
+			break
+		}
+		offset += j + 1
+	}
+	lineStart := offset
+	for i := 1; i < column && offset < len(b) && b[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRune(b[offset:])
+		offset += size
+	}
+	return offset, 1 + offset - lineStart
+}
+
+// checkMkDocs validates nav entries of the MkDocs configuration file config.
+// If reportUnlisted is true, it also reports files from the docs directory
+// that are not listed in the nav; checked are the files passed to Matcher
+// during traversal.
+func (st *checkState) checkMkDocs(config string, checked []string, reportUnlisted bool) ([]BrokenLink, error) {
+	b, err := fs.ReadFile(st.fsys, config)
+	if err != nil {
+		return nil, err
+	}
+	docsDir, entries, err := parseMkDocsConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", config, err)
+	}
+	docsDir = path.Join(path.Dir(config), docsDir)
+	var out []BrokenLink
+	listed := make(map[string]struct{})
+	for _, e := range entries {
+		target := path.Join(docsDir, strings.TrimPrefix(e.target, "/"))
+		if st.ignored(e.target) {
+			listed[target] = struct{}{}
+			continue
+		}
+		if !st.exists(target) {
+			out = append(out, BrokenLink{
+				File: config,
+				Link: LinkInfo{
+					Raw:       e.target,
+					Path:      e.target,
+					LineStart: e.line,
+					LineEnd:   e.line,
+					Line:      e.line,
+					Column:    e.column,
+					Offset:    e.offset,
+				},
+				kind: kindNavMissing,
+			})
+			continue
+		}
+		listed[target] = struct{}{}
+	}
+	if !reportUnlisted {
+		return out, nil
+	}
+	for _, p := range checked {
+		if docsDir != "." && !strings.HasPrefix(p, docsDir+"/") {
+			continue
+		}
+		if _, ok := listed[p]; !ok {
+			out = append(out, BrokenLink{File: p, kind: kindNotInNav})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package mdlinks
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// mountFS is the filesystem with other filesystems mounted at some of its
+// directories, see Checker.Mounts. Mounted filesystems hide directories of
+// the same name.
+type mountFS struct {
+	fs.FS
+	mounts map[string]fs.FS
+}
+
+func (m mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if fsys, ok := m.mounts[dir]; ok {
+			if dir == name {
+				return fsys.Open(".")
+			}
+			return fsys.Open(name[len(dir)+1:])
+		}
+	}
+	return m.FS.Open(name)
+}
+
+// withMounts returns fsys with c.Mounts mounted into it.
+func (c *Checker) withMounts(fsys fs.FS) (fs.FS, error) {
+	if len(c.Mounts) == 0 {
+		return fsys, nil
+	}
+	for dir := range c.Mounts {
+		if !fs.ValidPath(dir) || dir == "." {
+			return nil, fmt.Errorf("invalid mount point %q", dir)
+		}
+	}
+	return mountFS{FS: fsys, mounts: c.Mounts}, nil
+}
+
+// MonorepoFS returns the filesystem combining packages of a monorepo, keyed
+// by their paths in it, like “services/a” or “libs/b”, so that they're
+// checked as a single tree: links between packages, like
+// “../../libs/b/docs/setup.md” from “services/a/README.md”, are resolved
+// across package boundaries, and absolute links are relative to the root of
+// the monorepo. Package paths can't be nested.
+func MonorepoFS(packages map[string]fs.FS) (fs.FS, error) {
+	dirs := map[string][]string{".": nil} // directories above packages, with their entries
+	for p := range packages {
+		if !fs.ValidPath(p) || p == "." {
+			return nil, fmt.Errorf("invalid package path %q", p)
+		}
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			if _, ok := packages[dir]; ok {
+				return nil, fmt.Errorf("package path %q is nested in %q", p, dir)
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+	for p := range packages {
+		for child, dir := p, path.Dir(p); ; child, dir = dir, path.Dir(dir) {
+			_, seen := dirs[dir]
+			dirs[dir] = append(dirs[dir], path.Base(child))
+			if seen || dir == "." {
+				break
+			}
+		}
+	}
+	for _, names := range dirs {
+		sort.Strings(names)
+	}
+	return mountFS{FS: skeletonFS(dirs), mounts: packages}, nil
+}
+
+// skeletonFS is the filesystem of empty directories, keyed by their paths,
+// with names of their subdirectories as values.
+type skeletonFS map[string][]string
+
+func (s skeletonFS) Open(name string) (fs.File, error) {
+	names, ok := s[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, fs.FileInfoToDirEntry(skeletonInfo(name)))
+	}
+	return &dirFile{info: skeletonInfo(path.Base(name)), entries: entries}, nil
+}
+
+// dirFile is the open directory of in-memory filesystems.
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry // not yet read entries
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n > 0 && len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n <= 0 || n > len(d.entries) {
+		n = len(d.entries)
+	}
+	out := append([]fs.DirEntry(nil), d.entries[:n]...) // entries may be shared
+	d.entries = d.entries[n:]
+	return out, nil
+}
+
+// skeletonInfo describes the directory with the given name, which is not
+// backed by any file, like the ones of skeletonFS.
+type skeletonInfo string
+
+func (fi skeletonInfo) Name() string       { return string(fi) }
+func (fi skeletonInfo) Size() int64        { return 0 }
+func (fi skeletonInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (fi skeletonInfo) ModTime() time.Time { return time.Time{} }
+func (fi skeletonInfo) IsDir() bool        { return true }
+func (fi skeletonInfo) Sys() any           { return nil }
@@ -0,0 +1,156 @@
+package mdlinks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Move rewrites links in documents of fsys, so that they keep pointing to the
+// same files after the file or directory from is moved to to; both are fsys
+// paths. Relative links of the moved documents are rewritten to work from
+// their new location. Documents to process and the way links are resolved
+// are defined by c.
+//
+// Documents are saved with f.WriteFile under their current names, so Move
+// should be called before the actual move. It returns the applied fixes.
+func (f *Fixer) Move(c *Checker, fsys fs.FS, from, to string) ([]Fix, error) {
+	if f.WriteFile == nil {
+		return nil, errors.New("mdlinks: Fixer.WriteFile is nil")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: Move called with a nil Checker.Matcher")
+	}
+	from, to = path.Clean(from), path.Clean(to)
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if !st.exists(from) {
+		return nil, fmt.Errorf("mdlinks: %s: %w", from, fs.ErrNotExist)
+	}
+	moved := func(p string) string {
+		switch {
+		case p == from:
+			return to
+		case strings.HasPrefix(p, from+"/"):
+			return to + p[len(from):]
+		}
+		return p
+	}
+	var out []Fix
+	fn := func(p string) error {
+		links, err := st.movedLinks(p, moved)
+		if err != nil {
+			return err
+		}
+		fixes, err := f.rewrite(fsys, p, links)
+		out = append(out, fixes...)
+		return err
+	}
+	if err := c.walk(fsys, fn); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// movedLinks returns links of the document p that need to be rewritten once
+// files are moved according to the moved function, along with their new
+// values as suggestions.
+func (st *checkState) movedLinks(p string, moved func(string) string) ([]BrokenLink, error) {
+	docMeta, err := st.fileMeta(p)
+	if err != nil {
+		return nil, err
+	}
+	newPath := moved(p)
+	var out []BrokenLink
+	for _, s := range docMeta.links {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		target, err := st.resolve(p, s)
+		if err != nil {
+			return nil, err
+		}
+		if target == "" || !st.exists(target) {
+			continue
+		}
+		newTarget := moved(target)
+		if newTarget == target && (newPath == p || strings.HasPrefix(s.Path, "/")) {
+			continue
+		}
+		// link may rely on resolution rules, like an inferred extension:
+		// keep the part of the path that was in the link
+		if literal := linkPath(p, s); literal != target {
+			ext := strings.TrimPrefix(target, literal)
+			if ext == target || !strings.HasSuffix(newTarget, ext) {
+				continue
+			}
+			newTarget = strings.TrimSuffix(newTarget, ext)
+		}
+		raw := st.replaceLinkPath(s.Raw, newPath, newTarget)
+		if raw == s.Raw {
+			continue
+		}
+		out = append(out, BrokenLink{File: p, Link: s, Suggestion: raw})
+	}
+	return out, nil
+}
+
+// RenameAnchor rewrites links in documents of fsys pointing to the anchor
+// oldSlug of the document file, so that they point to newSlug instead. It's
+// meant to be used after the header of the document was changed. Documents
+// to process and the way links are resolved are defined by c. It returns the
+// applied fixes.
+func (f *Fixer) RenameAnchor(c *Checker, fsys fs.FS, file, oldSlug, newSlug string) ([]Fix, error) {
+	if f.WriteFile == nil {
+		return nil, errors.New("mdlinks: Fixer.WriteFile is nil")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: RenameAnchor called with a nil Checker.Matcher")
+	}
+	file = path.Clean(file)
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if !st.exists(file) {
+		return nil, fmt.Errorf("mdlinks: %s: %w", file, fs.ErrNotExist)
+	}
+	var out []Fix
+	fn := func(p string) error {
+		docMeta, err := st.fileMeta(p)
+		if err != nil {
+			return err
+		}
+		var links []BrokenLink
+		for _, s := range docMeta.links {
+			if s.Fragment != oldSlug || st.ignored(s.Raw) {
+				continue
+			}
+			target := p
+			if s.Path != "" {
+				if target, err = st.resolve(p, s); err != nil {
+					return err
+				}
+			}
+			if st.indexDocument(target) != file {
+				continue
+			}
+			raw := s.Raw
+			if i := strings.IndexByte(raw, '#'); i >= 0 {
+				raw = raw[:i]
+			}
+			links = append(links, BrokenLink{File: p, Link: s, Suggestion: raw + "#" + newSlug})
+		}
+		fixes, err := f.rewrite(fsys, p, links)
+		out = append(out, fixes...)
+		return err
+	}
+	if err := c.walk(fsys, fn); err != nil {
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,112 @@
+package mdlinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// isNotebook reports whether p is a Jupyter notebook. Notebooks matched by
+// Checker.Matcher are checked by their markdown cells.
+func isNotebook(p string) bool { return strings.EqualFold(path.Ext(p), ".ipynb") }
+
+// notebookCell describes the place of a markdown cell in the document built
+// by notebookMarkdown.
+type notebookCell struct {
+	index  int // 1-based, counting all cells of the notebook
+	offset int // offset of the first byte of the cell in the document
+	line   int // 1-based number of the first line of the cell in the document
+}
+
+// notebookMarkdown returns the markdown cells of the Jupyter notebook b
+// joined into a single document, with cells separated by blank lines, and
+// the places of these cells in it.
+func notebookMarkdown(b []byte) ([]byte, []notebookCell, error) {
+	var nb struct {
+		Cells []struct {
+			Type   string          `json:"cell_type"`
+			Source json.RawMessage `json:"source"`
+		} `json:"cells"`
+	}
+	if err := json.Unmarshal(b, &nb); err != nil {
+		return nil, nil, fmt.Errorf("parsing notebook: %w", err)
+	}
+	var buf strings.Builder
+	var cells []notebookCell
+	line := 1
+	for i, cell := range nb.Cells {
+		if cell.Type != "markdown" {
+			continue
+		}
+		// the source is either a string, or a list of lines with their
+		// line endings
+		var src string
+		if err := json.Unmarshal(cell.Source, &src); err != nil {
+			var lines []string
+			if err := json.Unmarshal(cell.Source, &lines); err != nil {
+				return nil, nil, fmt.Errorf("parsing notebook cell %d source: %w", i+1, err)
+			}
+			src = strings.Join(lines, "")
+		}
+		if src == "" {
+			continue
+		}
+		if !strings.HasSuffix(src, "\n") {
+			src += "\n"
+		}
+		cells = append(cells, notebookCell{index: i + 1, offset: buf.Len(), line: line})
+		buf.WriteString(src)
+		buf.WriteByte('\n')
+		line += strings.Count(src, "\n") + 1
+	}
+	return []byte(buf.String()), cells, nil
+}
+
+// notebookPositions makes positions of links and anchors of d, a document
+// built by notebookMarkdown, relative to the cells they're found in.
+func notebookPositions(d *docDetails, cells []notebookCell) {
+	// cellOf returns the cell containing the line
+	cellOf := func(line int) notebookCell {
+		var c notebookCell
+		for _, cell := range cells {
+			if cell.line > line {
+				break
+			}
+			c = cell
+		}
+		return c
+	}
+	fix := func(l *LinkInfo) {
+		line := l.Line
+		if line == 0 {
+			line = l.LineStart
+		}
+		if line == 0 {
+			return
+		}
+		c := cellOf(line)
+		l.Cell = c.index
+		if l.Line != 0 {
+			l.Line -= c.line - 1
+			l.Offset -= c.offset
+		}
+		if l.LineStart != 0 {
+			l.LineStart -= c.line - 1
+			l.LineEnd -= c.line - 1
+		}
+	}
+	for _, links := range [][]LinkInfo{d.links, d.wiki, d.hugo, d.unusedRefs, d.danglingRefs, d.empty, d.external, d.all} {
+		for i := range links {
+			fix(&links[i])
+		}
+	}
+	for i := range d.jekyll {
+		fix(&d.jekyll[i].LinkInfo)
+	}
+	for i := range d.anchorList {
+		a := &d.anchorList[i]
+		c := cellOf(a.Line)
+		a.Cell, a.Line, a.Offset = c.index, a.Line-c.line+1, a.Offset-c.offset
+	}
+}
@@ -0,0 +1,105 @@
+package mdlinks
+
+import (
+	"sort"
+	"strings"
+)
+
+// repoLinkPrefix returns the part of the link raw to a file of the repository
+// at repoURL, like “https://github.com/org/repo/blob/main/docs/x.md”, that
+// precedes the file path, like “https://github.com/org/repo/blob/main/”. It
+// returns false if raw doesn't point to a file or a directory of the
+// repository.
+func repoLinkPrefix(repoURL, raw string) (string, bool) {
+	if repoURL == "" {
+		return "", false
+	}
+	base := strings.TrimSuffix(repoURL, "/") + "/"
+	if len(raw) <= len(base) || !strings.EqualFold(raw[:len(base)], base) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(raw[len(base):], "-/") // GitLab style
+	kind, rest, ok := strings.Cut(rest, "/")
+	if !ok || kind != "blob" && kind != "tree" && kind != "raw" {
+		return "", false
+	}
+	ref, rest, ok := strings.Cut(rest, "/")
+	if !ok || ref == "" || rest == "" || rest[0] == '?' || rest[0] == '#' {
+		return "", false
+	}
+	return raw[:len(raw)-len(rest)], true
+}
+
+// siteLinkPrefix returns the part of the link raw to a page of the site at
+// siteURL, like “https://docs.example.com/v2/guide/setup.html”, that
+// precedes the path of the page relative to the site root, like
+// “https://docs.example.com/v2/”.
+func siteLinkPrefix(siteURL, raw string) (string, bool) {
+	if siteURL == "" {
+		return "", false
+	}
+	base := strings.TrimSuffix(siteURL, "/") + "/"
+	if len(raw) < len(base) || !strings.EqualFold(raw[:len(base)], base) {
+		return "", false
+	}
+	return raw[:len(base)], true
+}
+
+// ownLinkPrefix returns the part of the absolute link raw preceding the path
+// of the local file, if raw points to a file of the repository at
+// Checker.RepoURL or to a page of the site at Checker.SiteURL.
+func (st *checkState) ownLinkPrefix(raw string) (string, bool) {
+	if prefix, ok := repoLinkPrefix(st.c.RepoURL, raw); ok {
+		return prefix, true
+	}
+	return siteLinkPrefix(st.c.SiteURL, raw)
+}
+
+// ownLinks turns absolute links of d pointing to files of the repository at
+// Checker.RepoURL or to pages of the site at Checker.SiteURL into local links
+// relative to the root of fsys.
+func (st *checkState) ownLinks(d *docDetails) {
+	if st.c.RepoURL == "" && st.c.SiteURL == "" || len(d.external) == 0 {
+		return
+	}
+	// local returns l as a local link, if it points to a local file
+	local := func(l LinkInfo) (LinkInfo, bool) {
+		prefix, ok := st.ownLinkPrefix(l.Raw)
+		if !ok {
+			return l, false
+		}
+		u := localLink("/" + l.Raw[len(prefix):])
+		if u == nil {
+			return l, false
+		}
+		l.Path, l.Fragment = u.Path, u.Fragment
+		return l, true
+	}
+	var external []LinkInfo
+	for _, l := range d.external {
+		if l, ok := local(l); ok {
+			d.links = append(d.links, l)
+			continue
+		}
+		external = append(external, l)
+	}
+	if len(external) == len(d.external) {
+		return
+	}
+	d.external = external
+	for i, l := range d.all {
+		d.all[i], _ = local(l)
+	}
+	sort.SliceStable(d.links, func(i, j int) bool { return linkBefore(d.links[i], d.links[j]) })
+}
+
+// replaceLinkPath is like the replaceLinkPath function, but keeps links to
+// files of the repository at Checker.RepoURL and to pages of the site at
+// Checker.SiteURL absolute.
+func (st *checkState) replaceLinkPath(raw, p, target string) string {
+	prefix, ok := st.ownLinkPrefix(raw)
+	if !ok {
+		return replaceLinkPath(raw, p, target)
+	}
+	return prefix + strings.TrimPrefix(replaceLinkPath("/"+raw[len(prefix):], p, target), "/")
+}
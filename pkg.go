@@ -4,17 +4,24 @@ package mdlinks
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"net/http"
 	"net/url"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
-	"unicode"
+	"sync"
 	"unicode/utf8"
 
+	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Checker allows checks customization.
@@ -32,121 +39,1285 @@ type Checker struct {
 	// Matcher returns true, file is considered an utf-8 markdown document and
 	// is processed.
 	Matcher func(path string) (bool, error)
+
+	// CheckExternal enables verification of absolute http(s) urls found in
+	// documents. Each unique url is requested once; urls that fail to
+	// respond or respond with a client or server error status are reported
+	// as broken links.
+	CheckExternal bool
+
+	// HTTPClient is used to check external urls when CheckExternal is set.
+	// If nil, a client with a 10 second timeout is used.
+	HTTPClient *http.Client
+
+	// ExternalConcurrency limits the number of concurrent requests made
+	// when checking external urls. If zero, 8 is used.
+	ExternalConcurrency int
+
+	// Exclude, if not nil, is called for each file and directory CheckFS
+	// traverses, with the same path Matcher would get. If it returns true,
+	// the file is not checked, and the directory is skipped entirely. See
+	// ExcludePatterns for a way to build it from gitignore-style patterns.
+	Exclude func(path string, isDir bool) (bool, error)
+
+	// RespectGitignore makes CheckFS read .gitignore files found during
+	// traversal and skip files and directories they ignore.
+	RespectGitignore bool
+
+	// IgnoreLinks is a list of regular expressions (RE2 syntax) matched
+	// against link destinations as they appear in the document. Links
+	// matching any of these expressions are not checked.
+	IgnoreLinks []string
+
+	// WikiLinks enables checking of wiki-style links, like “[[Page]]” or
+	// “[[Page#Heading|text]]”, and selects how their targets are resolved.
+	WikiLinks WikiLinkMode
+
+	// HugoShortcodes enables checking of Hugo “{{< ref "page.md#anchor" >}}”
+	// and “{{< relref "page" >}}” shortcodes; fsys is considered to be the
+	// Hugo content directory.
+	HugoShortcodes bool
+
+	// JekyllTags enables checking of Jekyll “{% link path/to/file.md %}” and
+	// “{% post_url 2023-01-01-slug %}” tags; fsys is considered to be the
+	// Jekyll site source directory.
+	JekyllTags bool
+
+	// Extractors find links of custom syntaxes, like templating macros or
+	// include directives, in addition to the regular markdown and HTML
+	// links. Found links are checked the same way as the regular ones.
+	Extractors []LinkExtractor
+
+	// FileExtractors maps lowercase file extensions, like “.yaml”, to
+	// extractors of links of files with such extensions, so that files
+	// other than markdown, matched by Matcher, are checked too. Such files
+	// are not parsed as markdown: extractors are called with the nil doc.
+	// Fragments of links to such files are only checked for HTML files.
+	// See RegexpExtractor for a simple way to build an extractor.
+	FileExtractors map[string]LinkExtractor
+
+	// Dialect selects the flavor of markdown documents are parsed as.
+	Dialect Dialect
+
+	// Extensions are goldmark extensions used to parse documents in addition
+	// to the ones enabled by Dialect, so that documents relying on other
+	// syntaxes, like definition lists or math, are parsed the way they're
+	// rendered.
+	Extensions []goldmark.Extender
+
+	// ParserOptions are extra goldmark parser options used to parse
+	// documents, like additional block or inline parsers.
+	ParserOptions []parser.Option
+
+	// MkDocsConfig, if not empty, is a path to the MkDocs configuration file
+	// (usually “mkdocs.yml”) inside fsys. CheckFS then also verifies that
+	// every page referenced from its nav exists in the docs directory.
+	MkDocsConfig string
+
+	// MkDocsUnlisted makes CheckFS also report documents in the MkDocs docs
+	// directory that aren't referenced from the nav.
+	MkDocsUnlisted bool
+
+	// MdBookSummary, if not empty, is a path to the mdBook summary file
+	// (usually “src/SUMMARY.md”) inside fsys. Its links to non-existing
+	// chapters are then reported with a dedicated violation kind.
+	MdBookSummary string
+
+	// MdBookUnlisted makes CheckFS also report documents in the mdBook
+	// source directory that aren't referenced from the summary.
+	MdBookUnlisted bool
+
+	// DocusaurusLinks enables Docusaurus-style link resolution: links to
+	// non-existing paths are also considered valid if they point to a
+	// document without its “.md” or “.mdx” extension, to a directory with an
+	// “index.md” or “index.mdx” document, or to a document by its front
+	// matter slug or id.
+	DocusaurusLinks bool
+
+	// FrontMatterLinks is a list of front matter keys whose values are
+	// checked as local links, like “image” or “related”. Nested keys are
+	// separated by dots, like “params.image”. Values may be either strings
+	// or lists of strings.
+	FrontMatterLinks []string
+
+	// Redirects maps old paths to their new locations, either absolute
+	// /-separated paths inside fsys or absolute urls. Links to existing
+	// destinations of these redirects are considered valid. See
+	// ParseRedirects for a way to build it from a Netlify “_redirects”
+	// file.
+	Redirects map[string]string
+
+	// ReportRedirects makes CheckFS report links that are only valid
+	// because of Redirects as warnings.
+	ReportRedirects bool
+
+	// DirectoryIndex lists names of documents that represent a directory,
+	// like “README.md” or “index.md”. Links to directories are always
+	// considered valid; if DirectoryIndex is set, fragments of such links
+	// are checked against anchors of the first existing index document in
+	// that directory.
+	DirectoryIndex []string
+
+	// InferExtensions lists extensions, like “.md”, tried for links to
+	// non-existing paths: a link to “./page” is considered valid if
+	// “./page.md” exists. Fragments of such links are checked against the
+	// found document.
+	InferExtensions []string
+
+	// ExtensionMap maps extensions of published files to extensions of
+	// their sources, like “.html” to “.md”. A link to a non-existing
+	// “guide.html” is then considered valid if “guide.md” exists, and its
+	// fragment is checked against that document.
+	ExtensionMap map[string]string
+
+	// LintSpaces makes CheckFS report links with unencoded spaces in their
+	// targets, like “[x](<my file.md>)”, as warnings: not all renderers
+	// support them.
+	LintSpaces bool
+
+	// NormalizeUnicode makes CheckFS compare link paths and file names in
+	// Unicode normalization form C, so that links typed in one form match
+	// files stored in another, like NFD names on macOS.
+	NormalizeUnicode bool
+
+	// CheckCase makes CheckFS verify that link paths match the case of file
+	// names exactly. Links like “Readme.md” to the “README.md” file work on
+	// case-insensitive filesystems, like on macOS or Windows, but break
+	// elsewhere; these are reported along with the actual file name.
+	CheckCase bool
+
+	// UnusedReferences makes CheckFS report link reference definitions,
+	// like “[label]: target”, that are never used in their document, as
+	// warnings.
+	UnusedReferences bool
+
+	// PlaceholderLinks makes CheckFS report links with empty destinations,
+	// like “[text]()”, and obvious placeholders, like “#”, “TODO”, or
+	// “https://example.com”.
+	PlaceholderLinks bool
+
+	// LintPaths makes CheckFS report links to the document itself without
+	// a fragment, and links with redundant path segments, like
+	// “./a/../b.md”, along with their canonical form, as warnings.
+	LintPaths bool
+
+	// LinkStyle, if not LinkStyleAny, makes CheckFS report links to local
+	// files of the other form, relative or root-absolute, along with their
+	// rewritten form, as warnings.
+	LinkStyle LinkStyle
+
+	// RepoURL, if not empty, is the URL of the repository being checked,
+	// like “https://github.com/org/repo”. Absolute links to its files, like
+	// “https://github.com/org/repo/blob/main/docs/x.md#setup”, are checked
+	// as links to these files relative to the root of fsys, including
+	// fragments, instead of being treated as external links. The branch
+	// or tag part of such links is ignored.
+	RepoURL string
+
+	// SiteURL, if not empty, is the URL of the site published from fsys,
+	// like “https://docs.example.com/v2/”. Absolute links to its pages,
+	// like “https://docs.example.com/v2/guide/setup.html”, are checked as
+	// links to files relative to the root of fsys, like
+	// “/guide/setup.html”, with the path of SiteURL stripped. Combine it
+	// with ExtensionMap if pages are generated from files with other
+	// extensions.
+	SiteURL string
+
+	// QueryStrings selects how local links with query strings, like
+	// “page.md?highlight=x”, are treated. By default query strings are
+	// stripped, and the rest of the link is checked.
+	QueryStrings QueryPolicy
+
+	// CheckMailto makes CheckFS validate addresses of “mailto:” links,
+	// reporting links without addresses, with addresses that are not valid
+	// per RFC 5322, or with duplicate addresses as warnings.
+	CheckMailto bool
+
+	// OuterFS, if not nil, is the filesystem which directory OuterDir is
+	// the root of fsys, like the repository holding the checked “docs”
+	// directory. Links climbing above the root of fsys, like
+	// “../../outside.md”, are considered valid if their targets exist in
+	// OuterFS; their fragments are not checked. Links climbing above the
+	// root of OuterFS too, or any such links if OuterFS is nil, are
+	// reported as pointing outside of fsys.
+	OuterFS  fs.FS
+	OuterDir string
+
+	// Mounts, if not empty, maps directories of fsys, like “api” or
+	// “projects/lib”, to filesystems mounted there, like the generated API
+	// documentation or a sibling project. Links into these directories,
+	// like “/api/index.html#setup” or “../projects/lib/README.md”, are
+	// resolved against the mounted filesystems, hiding the directories of
+	// fsys with the same names. Files of mounted filesystems are link
+	// targets only; they're not checked themselves.
+	Mounts map[string]fs.FS
+
+	// TargetExists, if not nil, reports whether the local link target path
+	// exists in fsys, replacing the default check that opens the path. This
+	// allows treating generated files, or assets hosted elsewhere, as
+	// existing. Fragments of links to targets that exist only according to
+	// TargetExists are not checked.
+	TargetExists func(fsys fs.FS, path string) bool
+
+	// FragmentValid, if not nil, is called before reporting a link with the
+	// fragment not found among anchors of the target document, which path
+	// is targetPath; for links like “#fragment” it's the linking document
+	// itself. If it returns true, the link is considered valid. This
+	// allows accepting anchors generated at render time, like tabs or API
+	// reference ids.
+	FragmentValid func(targetPath, fragment string) (bool, error)
+
+	// Slugger, if not nil, generates anchors of headers from their plain
+	// text instead of the built-in algorithm, see Slugify. Explicit header
+	// ids, like “{#custom-id}”, are used as is, and repeated slugs still get
+	// numeric suffixes, like “-1”. Slugs may be percent-encoded, as they're
+	// decoded before comparing with link fragments.
+	Slugger func(text string) string
+
+	// Concurrency is the number of files CheckFS parses and checks at the
+	// same time; values below 2 make it process files one by one. Results
+	// are reported in the same order regardless of this setting, but
+	// function fields, like Matcher or Slugger, may then be called from
+	// multiple goroutines at once.
+	Concurrency int
+
+	// MaxViolations, if positive, is the number of broken links, including
+	// warnings, after which CheckFS and CheckFiles stop checking documents
+	// and report the ones found so far, so that huge trees produce bounded
+	// output and fail quickly. External links and checks of the whole
+	// project, like the MkDocs nav ones, are skipped once the limit is
+	// reached.
+	MaxViolations int
+
+	// OnBroken, if not nil, is called by CheckFS with each broken link as
+	// soon as it's found, before the check is complete. Broken links of a
+	// file are reported before OnFile is called for it. Links to
+	// unreachable external resources are only reported after all matched
+	// files are processed.
+	OnBroken func(BrokenLink)
+
+	// OnFile, if not nil, is called by CheckFS with the path of each file
+	// matched by Matcher once that file is processed.
+	OnFile func(path string)
+}
+
+// CheckFS walks file system fsys looking for files using the Matcher function.
+// It parses matched files as markdown, looks for local urls (urls that don't
+// have schema and domain), and reports if it finds any urls pointing to
+// non-existing files.
+//
+// If error returned is a *BrokenLinksError, it describes found files with
+// broken links.
+func (c *Checker) CheckFS(fsys fs.FS) error {
+	if c == nil {
+		panic("mdlinks: CheckFS called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: CheckFS called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return err
+	}
+	_, brokenLinks, err := st.checkAll()
+	if err != nil {
+		return err
+	}
+	if len(brokenLinks) != 0 {
+		return &BrokenLinksError{Links: brokenLinks}
+	}
+	return nil
+}
+
+// checkAll checks all files matched by c.walk, and the whole project, the
+// way CheckFS does. It returns paths of checked files, in traversal order,
+// and broken links found.
+func (st *checkState) checkAll() ([]string, []BrokenLink, error) {
+	c := st.c
+	var brokenLinks []BrokenLink
+	var checked []string // matched files, in traversal order
+	fileOrder := make(map[string]int)
+	fn := func(p string, links []BrokenLink) error {
+		fileOrder[p] = len(fileOrder)
+		checked = append(checked, p)
+		links = c.limitViolations(brokenLinks, links)
+		brokenLinks = append(brokenLinks, links...)
+		c.onBroken(links)
+		if c.OnFile != nil {
+			c.OnFile(p)
+		}
+		if c.reachedLimit(brokenLinks) {
+			return errStopped
+		}
+		return nil
+	}
+	if err := st.checkFiles(fn); err == errStopped {
+		return checked, brokenLinks, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if len(st.external) != 0 {
+		links := c.limitViolations(brokenLinks, c.checkExternal(st.external))
+		c.onBroken(links)
+		brokenLinks = append(brokenLinks, links...)
+		// keep reports grouped by file in the traversal order
+		sort.SliceStable(brokenLinks, func(i, j int) bool {
+			return fileOrder[brokenLinks[i].File] < fileOrder[brokenLinks[j].File]
+		})
+	}
+	if c.reachedLimit(brokenLinks) {
+		return checked, brokenLinks, nil
+	}
+	links, err := st.checkProject(checked)
+	if err != nil {
+		return nil, nil, err
+	}
+	links = c.limitViolations(brokenLinks, links)
+	c.onBroken(links)
+	return checked, append(brokenLinks, links...), nil
+}
+
+// limitViolations returns links, truncated so that together with the found
+// ones they don't exceed c.MaxViolations.
+func (c *Checker) limitViolations(found, links []BrokenLink) []BrokenLink {
+	if c.MaxViolations > 0 && len(found)+len(links) > c.MaxViolations {
+		return links[:c.MaxViolations-len(found)]
+	}
+	return links
+}
+
+// reachedLimit reports whether the found broken links reached
+// c.MaxViolations.
+func (c *Checker) reachedLimit(found []BrokenLink) bool {
+	return c.MaxViolations > 0 && len(found) >= c.MaxViolations
+}
+
+// LimitReached reports whether broken links, as returned by a check with c,
+// reached c.MaxViolations, so that the check stopped before checking all
+// documents.
+func (c *Checker) LimitReached(links []BrokenLink) bool {
+	return c.reachedLimit(links)
+}
+
+// CheckFile checks a single document name of fsys the same way CheckFS
+// does, resolving its links and their fragments against fsys. It returns
+// broken links of the document, and a nil error if the check succeeded,
+// even if some links are broken. The document is checked regardless of
+// Matcher, yet Matcher is still used to tell markdown link targets apart.
+// Checks of the whole project, like the MkDocs nav ones, are skipped.
+func (c *Checker) CheckFile(fsys fs.FS, name string) ([]BrokenLink, error) {
+	if c == nil {
+		panic("mdlinks: CheckFile called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: CheckFile called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return st.checkDocument(name)
+}
+
+// CheckFiles is like CheckFile, but checks several documents of fsys at
+// once, parsing their shared link targets only once. Broken links are
+// grouped by document in the order of names, followed by links to
+// unreachable external resources.
+func (c *Checker) CheckFiles(fsys fs.FS, names ...string) ([]BrokenLink, error) {
+	if c == nil {
+		panic("mdlinks: CheckFiles called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: CheckFiles called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	var out []BrokenLink
+	for _, name := range names {
+		links, err := st.checkFile(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c.limitViolations(out, links)...)
+		if c.reachedLimit(out) {
+			return out, nil
+		}
+	}
+	if len(st.external) != 0 {
+		out = append(out, c.limitViolations(out, c.checkExternal(st.external))...)
+	}
+	return out, nil
+}
+
+// CheckBytes checks the markdown document body the same way CheckFile does,
+// as if it was stored in fsys under the name path, which may not exist. This
+// allows validating generated documents before publishing them.
+func (c *Checker) CheckBytes(fsys fs.FS, name string, body []byte) ([]BrokenLink, error) {
+	if c == nil {
+		panic("mdlinks: CheckBytes called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: CheckBytes called with a nil Checker.Matcher")
+	}
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "check", Path: name, Err: fs.ErrInvalid}
+	}
+	if !utf8.Valid(body) {
+		return nil, fmt.Errorf("%s is not a valid utf8 file", name)
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	docMeta, err := st.parse(name, body)
+	if err != nil {
+		return nil, err
+	}
+	st.seen[name] = docMeta
+	st.virtual = name
+	return st.checkDocument(name)
+}
+
+// CheckReader is like CheckBytes, but reads the document from r.
+func (c *Checker) CheckReader(fsys fs.FS, name string, r io.Reader) ([]BrokenLink, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.CheckBytes(fsys, name, body)
+}
+
+// checkDocument returns broken links of the single document p, including
+// the external ones.
+func (st *checkState) checkDocument(p string) ([]BrokenLink, error) {
+	links, err := st.checkFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(st.external) != 0 {
+		links = append(links, st.c.checkExternal(st.external)...)
+	}
+	return links, nil
+}
+
+// stream runs the checks of CheckFS over fsys, calling yield with broken
+// links as soon as they're found. Links to unreachable external resources
+// are only reported after the links of all matched files, followed by the
+// MkDocs and mdBook ones. It stops early if yield returns false.
+func (c *Checker) stream(fsys fs.FS, yield func(BrokenLink) bool) error {
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return err
+	}
+	var checked []string
+	fn := func(p string, links []BrokenLink) error {
+		checked = append(checked, p)
+		for _, l := range links {
+			if c.OnBroken != nil {
+				c.OnBroken(l)
+			}
+			if !yield(l) {
+				return errStopped
+			}
+		}
+		if c.OnFile != nil {
+			c.OnFile(p)
+		}
+		return nil
+	}
+	if err := st.checkFiles(fn); err != nil {
+		if err == errStopped {
+			return nil
+		}
+		return err
+	}
+	var links []BrokenLink
+	if len(st.external) != 0 {
+		links = c.checkExternal(st.external)
+	}
+	project, err := st.checkProject(checked)
+	if err != nil {
+		return err
+	}
+	for _, l := range append(links, project...) {
+		if c.OnBroken != nil {
+			c.OnBroken(l)
+		}
+		if !yield(l) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// onBroken calls c.OnBroken, if set, for each of the links.
+func (c *Checker) onBroken(links []BrokenLink) {
+	if c.OnBroken == nil {
+		return
+	}
+	for _, l := range links {
+		c.OnBroken(l)
+	}
+}
+
+// errStopped is used to stop the walk when callers need no more results.
+var errStopped = errors.New("stopped")
+
+// checkProject returns broken links found by the checks of the whole
+// project, like the MkDocs nav or mdBook summary ones, that need the list
+// of all the checked files.
+func (st *checkState) checkProject(checked []string) ([]BrokenLink, error) {
+	var out []BrokenLink
+	if st.c.MdBookSummary != "" && st.c.MdBookUnlisted {
+		links, err := st.checkMdBookUnlisted(st.c.MdBookSummary, checked)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, links...)
+	}
+	if st.c.MkDocsConfig != "" {
+		links, err := st.checkMkDocs(st.c.MkDocsConfig, checked, st.c.MkDocsUnlisted)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, links...)
+	}
+	return out, nil
+}
+
+// checkFiles checks each file matched by c.walk, using up to c.Concurrency
+// goroutines, and calls fn with the found broken links of each file in the
+// traversal order.
+func (st *checkState) checkFiles(fn func(p string, links []BrokenLink) error) error {
+	if st.c.Concurrency < 2 {
+		return st.c.walk(st.fsys, func(p string) error {
+			links, err := st.checkFile(p)
+			if err != nil {
+				return err
+			}
+			return fn(p, links)
+		})
+	}
+	var files []string
+	if err := st.c.walk(st.fsys, func(p string) error {
+		files = append(files, p)
+		return nil
+	}); err != nil {
+		return err
+	}
+	type result struct {
+		links []BrokenLink
+		err   error
+		done  chan struct{}
+	}
+	results := make([]result, len(files))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	jobs := make(chan int)
+	stop := make(chan struct{}) // closed on return to stop the workers
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(stop)
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	for i := 0; i < st.c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j].links, results[j].err = st.checkFile(files[j])
+				close(results[j].done)
+			}
+		}()
+	}
+	for i, p := range files {
+		<-results[i].done
+		if err := results[i].err; err != nil {
+			return err
+		}
+		if err := fn(p, results[i].links); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls fn for each file of fsys matched by c.Matcher, skipping the ones
+// excluded by c.Exclude or, if c.RespectGitignore is set, .gitignore files.
+func (c *Checker) walk(fsys fs.FS, fn func(p string) error) error {
+	gitignores := &gitignoreSet{fsys: fsys}
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == ".git" || c.Mounts[p] != nil) {
+			return fs.SkipDir
+		}
+		if c.Exclude != nil && p != "." {
+			switch skip, err := c.Exclude(p, d.IsDir()); {
+			case err != nil:
+				return err
+			case skip && d.IsDir():
+				return fs.SkipDir
+			case skip:
+				return nil
+			}
+		}
+		if c.RespectGitignore && p != "." && gitignores.excluded(p, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if c.RespectGitignore {
+				return gitignores.load(p)
+			}
+			return nil
+		}
+		switch ok, err := c.Matcher(p); {
+		case err != nil:
+			return err
+		case !ok:
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// checkState holds the state of a single CheckFS call.
+type checkState struct {
+	c       *Checker
+	fsys    fs.FS
+	opts    *docOptions
+	ignored func(link string) bool
+	virtual string // path of the document not stored in fsys, see CheckBytes
+
+	// mu guards the fields below, as files may be checked concurrently
+	mu sync.Mutex
+
+	// track processed files to make sure each one is processed only once,
+	// even if we need to get back to it at a later time to get its header
+	// ids. Keys are full fsys paths.
+	seen     map[string]*docDetails
+	seenHTML map[string]*docDetails
+
+	external []BrokenLink // candidates for external checks
+
+	nameIndex map[string][]string // see filesNamed
+	nfcIndex  map[string]string   // see normalizedPath
+	dirNames  map[string][]string // see caseMismatch
+	slugIndex map[string]string   // see buildSlugIndex
+
+	labelIndex  map[string]struct{}        // see rstLabels
+	goDeclIndex map[string]map[string]bool // see goDecls
+	lineCounts  map[string]int             // see linesInRange
+}
+
+func (c *Checker) newCheckState(fsys fs.FS) (*checkState, error) {
+	ignored, err := compileIgnores(c.IgnoreLinks)
+	if err != nil {
+		return nil, err
+	}
+	if fsys, err = c.withMounts(fsys); err != nil {
+		return nil, err
+	}
+	return &checkState{
+		c:        c,
+		fsys:     fsys,
+		opts:     c.docOptions(),
+		ignored:  ignored,
+		seen:     make(map[string]*docDetails),
+		seenHTML: make(map[string]*docDetails),
+	}, nil
+}
+
+func (st *checkState) exists(p string) bool {
+	if p == st.virtual && p != "" {
+		return true
+	}
+	if st.c.TargetExists != nil {
+		return st.c.TargetExists(st.fsys, p)
+	}
+	f, err := st.fsys.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return true
+}
+
+// outerPath returns the path of target, climbing above the root of fsys,
+// like “../outside.md”, in Checker.OuterFS. It returns false if OuterFS is
+// not set, or if target climbs above its root too.
+func (st *checkState) outerPath(target string) (string, bool) {
+	if st.c.OuterFS == nil {
+		return "", false
+	}
+	p := path.Join(st.c.OuterDir, target)
+	return p, fs.ValidPath(p)
+}
+
+// fileMeta returns details of the markdown document p.
+func (st *checkState) fileMeta(p string) (*docDetails, error) {
+	st.mu.Lock()
+	docMeta, ok := st.seen[p]
+	st.mu.Unlock()
+	if ok {
+		return docMeta, nil
+	}
+	b, err := fs.ReadFile(st.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(b) {
+		return nil, fmt.Errorf("%s is not a valid utf8 file", p)
+	}
+	if docMeta, err = st.parse(p, b); err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if prev, ok := st.seen[p]; ok { // parsed concurrently
+		return prev, nil
+	}
+	st.seen[p] = docMeta
+	return docMeta, nil
+}
+
+// parse extracts details of the matched document p with the body b.
+// Files with extensions listed in Checker.FileExtractors are parsed by these
+// extractors, Jupyter notebooks by their markdown cells, Go files by their
+// doc comments, AsciiDoc and reStructuredText documents with their own
+// parsers. Absolute links to files of the repository at Checker.RepoURL and
+// to pages of the site at Checker.SiteURL are turned into local ones.
+func (st *checkState) parse(p string, b []byte) (*docDetails, error) {
+	docMeta, err := st.parseFile(p, b)
+	if err != nil {
+		return nil, err
+	}
+	st.ownLinks(docMeta)
+	setQueries(docMeta)
+	return docMeta, nil
+}
+
+// parseFile picks the parser for the document p by its type.
+func (st *checkState) parseFile(p string, b []byte) (*docDetails, error) {
+	if extract := st.c.FileExtractors[strings.ToLower(path.Ext(p))]; extract != nil {
+		return extractFileDetails(p, b, extract)
+	}
+	if isAsciiDoc(p) {
+		return extractAsciiDocDetails(b), nil
+	}
+	if isRST(p) {
+		return extractRSTDetails(b), nil
+	}
+	if isGoFile(p) {
+		return st.parseGoFile(p, b)
+	}
+	if !isNotebook(p) {
+		return extractDocDetails(b, st.opts)
+	}
+	b, cells, err := notebookMarkdown(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	docMeta, err := extractDocDetails(b, st.opts)
+	if err != nil {
+		return nil, err
+	}
+	notebookPositions(docMeta, cells)
+	return docMeta, nil
+}
+
+// htmlMeta returns details of the html document p; only anchors are filled.
+func (st *checkState) htmlMeta(p string) (*docDetails, error) {
+	st.mu.Lock()
+	docMeta, ok := st.seenHTML[p]
+	st.mu.Unlock()
+	if ok {
+		return docMeta, nil
+	}
+	b, err := fs.ReadFile(st.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	docMeta = &docDetails{anchors: make(map[string]struct{})}
+	for _, id := range htmlAnchors(b) {
+		docMeta.anchors[id] = struct{}{}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if prev, ok := st.seenHTML[p]; ok { // parsed concurrently
+		return prev, nil
+	}
+	st.seenHTML[p] = docMeta
+	return docMeta, nil
+}
+
+// indexDocument returns the path of the first existing DirectoryIndex
+// document if p is a directory, or p otherwise.
+func (st *checkState) indexDocument(p string) string {
+	if len(st.c.DirectoryIndex) == 0 {
+		return p
+	}
+	if fi, err := fs.Stat(st.fsys, p); err == nil && fi.IsDir() {
+		for _, name := range st.c.DirectoryIndex {
+			if cand := path.Join(p, name); st.exists(cand) {
+				return cand
+			}
+		}
+	}
+	return p
+}
+
+// targetMeta returns details of the link target p if it's a document that
+// can have anchors (matched markdown or html file), or nil otherwise.
+func (st *checkState) targetMeta(p string) (*docDetails, error) {
+	p = st.indexDocument(p)
+	if st.c.TargetExists != nil && p != st.virtual {
+		// target may exist only according to TargetExists
+		if _, err := fs.Stat(st.fsys, p); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	switch ok, _ := st.c.Matcher(p); {
+	case ok && st.c.FileExtractors[strings.ToLower(path.Ext(p))] != nil && !isHTMLFile(p):
+		return nil, nil // no anchors
+	case ok:
+		return st.fileMeta(p)
+	case isHTMLFile(p):
+		return st.htmlMeta(p)
+	}
+	return nil, nil
+}
+
+// filesNamed returns paths of all files with the given base name. On the
+// first call it walks the whole filesystem to build an index.
+func (st *checkState) filesNamed(base string) ([]string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.nameIndex == nil {
+		index := make(map[string][]string)
+		fn := func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			if !d.IsDir() {
+				index[d.Name()] = append(index[d.Name()], p)
+			}
+			return nil
+		}
+		if err := fs.WalkDir(st.fsys, ".", fn); err != nil {
+			return nil, err
+		}
+		st.nameIndex = index
+	}
+	return st.nameIndex[base], nil
+}
+
+// normalizedPath returns the path of an existing file or directory that
+// matches p when both are converted to Unicode normalization form C, or an
+// empty string if there's no such file. On the first call it walks the whole
+// filesystem to build an index.
+func (st *checkState) normalizedPath(p string) (string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.nfcIndex == nil {
+		index := make(map[string]string)
+		fn := func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			if key := norm.NFC.String(p); key != p {
+				index[key] = p
+			}
+			return nil
+		}
+		if err := fs.WalkDir(st.fsys, ".", fn); err != nil {
+			return "", err
+		}
+		st.nfcIndex = index
+	}
+	key := norm.NFC.String(p)
+	if alt, ok := st.nfcIndex[key]; ok {
+		return alt, nil
+	}
+	if key != p && st.exists(key) {
+		return key, nil
+	}
+	return "", nil
+}
+
+// caseMismatch returns the path of an existing file or directory that matches
+// p only if the case is ignored. It returns an empty string if p matches an
+// existing path exactly, or there's no matching path at all.
+func (st *checkState) caseMismatch(p string) (string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dirNames == nil {
+		st.dirNames = make(map[string][]string)
+	}
+	var mismatch bool
+	dir := "."
+	for _, name := range strings.Split(path.Clean(p), "/") {
+		names, ok := st.dirNames[dir]
+		if !ok {
+			entries, err := fs.ReadDir(st.fsys, dir)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			st.dirNames[dir] = names
+		}
+		var found string
+		for _, n := range names {
+			if n == name {
+				found = n
+				break
+			}
+			if found == "" && strings.EqualFold(n, name) {
+				found = n
+			}
+		}
+		if found == "" {
+			return "", nil
+		}
+		if found != name {
+			mismatch = true
+		}
+		dir = path.Join(dir, found)
+	}
+	if !mismatch {
+		return "", nil
+	}
+	return dir, nil
+}
+
+// fragmentValid calls the FragmentValid hook, if set, for the fragment not
+// found in the document target.
+func (st *checkState) fragmentValid(target, fragment string) (bool, error) {
+	if st.c.FragmentValid == nil {
+		return false, nil
+	}
+	return st.c.FragmentValid(target, fragment)
+}
+
+// resolveMissing tries to find a file that a link to non-existing fsys path p
+// may point to, using the optional resolution rules. It returns an empty
+// string if there's no such file.
+func (st *checkState) resolveMissing(p string) (string, error) {
+	if ext := path.Ext(p); ext != "" {
+		if src, ok := st.c.ExtensionMap[ext]; ok {
+			if cand := strings.TrimSuffix(p, ext) + src; st.exists(cand) {
+				return cand, nil
+			}
+		}
+	}
+	for _, ext := range st.c.InferExtensions {
+		if cand := strings.TrimSuffix(p, "/") + ext; st.exists(cand) {
+			return cand, nil
+		}
+	}
+	if st.c.DocusaurusLinks {
+		return st.resolveDocusaurus(p)
+	}
+	return "", nil
 }
 
-// CheckFS walks file system fsys looking for files using the Matcher function.
-// It parses matched files as markdown, looks for local urls (urls that don't
-// have schema and domain), and reports if it finds any urls pointing to
-// non-existing files.
-//
-// If error returned is a *BrokenLinksError, it describes found files with
-// broken links.
-func (c *Checker) CheckFS(fsys fs.FS) error {
-	if c == nil {
-		panic("mdlinks: CheckFS called on a nil Checker")
+// linkPath returns fsys path that the link s from the document p literally
+// points to, or an empty string for links without a path.
+func linkPath(p string, s LinkInfo) string {
+	switch {
+	case s.Path == "":
+		return ""
+	case s.Path[0] == '/': // e.g. “/abc”
+		return path.Clean(s.Path)[1:]
 	}
-	if c.Matcher == nil {
-		panic("mdlinks: CheckFS called with a nil Checker.Matcher")
+	return path.Join(path.Dir(p), s.Path) // e.g. “abc” or “../abc”
+}
+
+// resolve returns fsys path that the link s from the document p points to,
+// or an empty string for links without a path, like “#fragment”. If the
+// literal target doesn't exist, it tries the optional resolution rules; the
+// path returned may still not exist.
+func (st *checkState) resolve(p string, s LinkInfo) (string, error) {
+	srel := linkPath(p, s) // fs.FS relative path that link points to
+	// decoded path doesn't exist, but the file may have percent
+	// sign in its name, like “100%25.md”
+	if srel != "" && strings.Contains(s.Raw, "%") && !st.exists(srel) {
+		var literal string
+		if raw := rawLinkPath(s.Raw); strings.HasPrefix(raw, "/") {
+			literal = raw[1:]
+		} else {
+			literal = path.Join(path.Dir(p), raw)
+		}
+		if st.exists(literal) {
+			srel = literal
+		}
 	}
-	exists := func(p string) bool {
-		f, err := fsys.Open(p)
+	if srel != "" && st.c.NormalizeUnicode && !st.exists(srel) {
+		alt, err := st.normalizedPath(srel)
 		if err != nil {
-			return false
+			return "", err
 		}
-		defer f.Close()
-		return true
-	}
-	// track processed files to make sure each one is processed only once, even
-	// if we need to get back to it at a later time to get its header ids. Keys
-	// are full fsys paths.
-	seen := make(map[string]*docDetails)
-	getFileMeta := func(p string) (*docDetails, error) {
-		docMeta, ok := seen[p]
-		if ok {
-			return docMeta, nil
+		if alt != "" {
+			srel = alt
 		}
-		b, err := fs.ReadFile(fsys, p)
+	}
+	if srel != "" && !st.exists(srel) {
+		alt, err := st.resolveMissing(srel)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		if !utf8.Valid(b) {
-			return nil, fmt.Errorf("%s is not a valid utf8 file", p)
+		if alt != "" {
+			srel = alt
 		}
-		if docMeta, err = extractDocDetails(b); err != nil {
-			return nil, err
+	}
+	return srel, nil
+}
+
+// checkFile checks links of the markdown document p and returns the broken
+// ones. External links are only collected into st.external.
+func (st *checkState) checkFile(p string) ([]BrokenLink, error) {
+	docMeta, err := st.fileMeta(p)
+	if err != nil {
+		return nil, err
+	}
+	var extra []BrokenLink // links found separately from the regular ones
+	if st.c.PlaceholderLinks {
+		for _, s := range docMeta.empty {
+			kind := kindEmptyLink
+			if s.Raw != "" {
+				kind = kindPlaceholderLink
+			}
+			extra = append(extra, BrokenLink{File: p, Link: s, kind: kind})
+		}
+	}
+	for _, s := range docMeta.external {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		if st.c.PlaceholderLinks && isPlaceholderLink(s.Raw) {
+			extra = append(extra, BrokenLink{File: p, Link: s, kind: kindPlaceholderLink})
+			continue
+		}
+		if st.c.CheckExternal {
+			st.mu.Lock()
+			st.external = append(st.external, BrokenLink{File: p, Link: s, kind: kindDeadExternal})
+			st.mu.Unlock()
+		}
+	}
+	if st.c.CheckMailto {
+		for _, s := range docMeta.all {
+			if !isMailtoLink(s.Raw) || st.ignored(s.Raw) {
+				continue
+			}
+			if kind, ok := mailtoProblem(s.Raw); ok {
+				extra = append(extra, BrokenLink{File: p, Link: s, kind: kind})
+			}
 		}
-		seen[p] = docMeta
-		return docMeta, nil
 	}
 	var brokenLinks []BrokenLink
-	fn := func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	for _, s := range docMeta.links {
+		if st.ignored(s.Raw) {
+			continue
 		}
-		if d.IsDir() && d.Name() == ".git" {
-			return fs.SkipDir
+		if st.c.PlaceholderLinks && isPlaceholderLink(s.Raw) {
+			brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindPlaceholderLink})
+			continue
 		}
-		if d.IsDir() {
-			return nil
+		if st.c.LintSpaces && strings.ContainsAny(s.Raw, " \t") {
+			brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindUnencodedSpace})
 		}
-		switch ok, err := c.Matcher(p); {
-		case err != nil:
-			return err
-		case !ok:
-			return nil
+		if s.Query != "" {
+			switch st.c.QueryStrings {
+			case QueryWarn:
+				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindQueryString})
+			case QueryError:
+				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindForbiddenQuery})
+				continue
+			}
 		}
-		docMeta, err := getFileMeta(p)
+		srel, err := st.resolve(p, s)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		for _, s := range docMeta.links {
-			var srel string // fs.FS relative path that link points to
-
-			if s.Path != "" && s.Path[0] == '/' { // e.g. “/abc”
-				srel = s.Path[1:]
-			} else if s.Path != "" { // e.g. “abc” or “../abc”
-				srel = path.Join(strings.TrimSuffix(p, d.Name()), s.Path)
+		if srel == ".." || strings.HasPrefix(srel, "../") {
+			if outer, ok := st.outerPath(srel); !ok {
+				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindOutsideRoot})
+			} else if _, err := fs.Stat(st.c.OuterFS, outer); err != nil {
+				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindFileNotExists})
+			}
+			continue
+		}
+		if srel != "" && st.c.CheckCase {
+			actual, err := st.caseMismatch(srel)
+			if err != nil {
+				return nil, err
 			}
-			// path is non-empty
-			if srel != "" && !exists(srel) {
-				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s})
+			if actual != "" {
+				brokenLinks = append(brokenLinks, BrokenLink{
+					File:       p,
+					Link:       s,
+					Suggestion: st.replaceLinkPath(s.Raw, p, actual),
+					kind:       kindCaseMismatch,
+				})
 				continue
 			}
-			// path is empty, and fragment is non-empty (internal link)
-			if s.Path == "" && s.Fragment != "" { // internal link
-				if _, ok := docMeta.anchors[s.Fragment]; !ok {
-					brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindBrokenInternalAnchor})
-					continue
+		}
+		if srel != "" && !st.exists(srel) {
+			if dest, ok := st.redirectTarget(srel); ok && (isExternalLink(dest) || st.exists(dest)) {
+				if st.c.ReportRedirects {
+					brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindViaRedirect})
 				}
-			}
-			if srel == "" || s.Fragment == "" {
 				continue
 			}
-			if ok, _ := c.Matcher(srel); !ok {
-				continue
+			kind := kindFileNotExists
+			if p == st.c.MdBookSummary {
+				kind = kindSummaryMissing
 			}
-			// path is non-empty, fragment is non-empty, path points to the markdown file
-			meta2, err := getFileMeta(srel)
+			suggestion, err := st.suggestFile(p, s.Raw, srel)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			if _, ok := meta2.anchors[s.Fragment]; !ok {
+			brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, Suggestion: suggestion, kind: kind})
+			continue
+		}
+		if srel != "" && st.c.LintPaths {
+			if l, ok := st.lintPath(p, s, srel); ok {
+				brokenLinks = append(brokenLinks, l)
+			}
+		}
+		if st.c.LinkStyle != LinkStyleAny {
+			if l, ok := st.lintStyle(p, s); ok {
+				brokenLinks = append(brokenLinks, l)
+			}
+		}
+		// path is empty, and fragment is non-empty (internal link)
+		if s.Path == "" && s.Fragment != "" { // internal link
+			if _, ok := docMeta.anchors[s.Fragment]; !ok {
+				switch valid, err := st.fragmentValid(p, s.Fragment); {
+				case err != nil:
+					return nil, err
+				case valid:
+					continue
+				}
 				brokenLinks = append(brokenLinks, BrokenLink{
-					File: p,
-					Link: s,
-					kind: kindBrokenExternalAnchor,
+					File:       p,
+					Link:       s,
+					Suggestion: suggestAnchor(s.Raw, s.Fragment, docMeta.anchors),
+					kind:       kindBrokenInternalAnchor,
 				})
+				continue
 			}
 		}
-		return nil
+		if srel == "" || s.Fragment == "" {
+			continue
+		}
+		if first, last, ok := lineFragment(s.Fragment); ok {
+			switch ok, err := st.linesInRange(srel, first, last); {
+			case err != nil:
+				return nil, err
+			case !ok:
+				brokenLinks = append(brokenLinks, BrokenLink{File: p, Link: s, kind: kindLineOutOfRange})
+			}
+			continue
+		}
+		// path is non-empty, fragment is non-empty, path points to the
+		// markdown or html file
+		meta2, err := st.targetMeta(srel)
+		if err != nil {
+			return nil, err
+		}
+		if meta2 == nil {
+			continue
+		}
+		if _, ok := meta2.anchors[s.Fragment]; !ok {
+			switch valid, err := st.fragmentValid(st.indexDocument(srel), s.Fragment); {
+			case err != nil:
+				return nil, err
+			case valid:
+				continue
+			}
+			brokenLinks = append(brokenLinks, BrokenLink{
+				File:       p,
+				Link:       s,
+				Suggestion: suggestAnchor(s.Raw, s.Fragment, meta2.anchors),
+				kind:       kindBrokenExternalAnchor,
+			})
+		}
 	}
-	if err := fs.WalkDir(fsys, ".", fn); err != nil {
-		return err
+	if st.c.WikiLinks != WikiLinksDisabled {
+		links, err := st.checkWikiLinks(p, docMeta)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, links...)
 	}
-	if len(brokenLinks) != 0 {
-		return &BrokenLinksError{Links: brokenLinks}
+	if st.c.HugoShortcodes {
+		links, err := st.checkHugoRefs(p, docMeta)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, links...)
 	}
-	return nil
+	if st.c.JekyllTags {
+		links, err := st.checkJekyllTags(p, docMeta)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, links...)
+	}
+	if len(docMeta.rstRefs) != 0 {
+		links, err := st.checkRSTRefs(p, docMeta)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, links...)
+	}
+	for _, s := range docMeta.undeclared {
+		if !st.ignored(s.Raw) {
+			extra = append(extra, BrokenLink{File: p, Link: s, kind: kindUndeclaredIdentifier})
+		}
+	}
+	for _, s := range docMeta.danglingRefs {
+		if !st.ignored(s.Raw) {
+			extra = append(extra, BrokenLink{File: p, Link: s, kind: kindMissingReference})
+		}
+	}
+	if st.c.UnusedReferences {
+		for _, s := range docMeta.unusedRefs {
+			extra = append(extra, BrokenLink{File: p, Link: s, kind: kindUnusedReference})
+		}
+	}
+	if len(extra) != 0 {
+		brokenLinks = append(brokenLinks, extra...)
+		// keep reports in the document order
+		sort.SliceStable(brokenLinks, func(i, j int) bool {
+			return linkBefore(brokenLinks[i].Link, brokenLinks[j].Link)
+		})
+	}
+	return brokenLinks, nil
 }
 
 // CheckFS walks file system fsys looking for files with their base names
@@ -166,15 +1337,107 @@ func CheckFS(fsys fs.FS, pat string) error {
 	return c.CheckFS(fsys)
 }
 
+// compileIgnores compiles regular expressions from exprs and returns a function
+// reporting whether its argument matches any of them.
+func compileIgnores(exprs []string) (func(string) bool, error) {
+	var res []*regexp.Regexp
+	for _, s := range exprs {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("mdlinks: invalid ignore expression: %w", err)
+		}
+		res = append(res, re)
+	}
+	return func(link string) bool {
+		for _, re := range res {
+			if re.MatchString(link) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// docOptions controls how documents are parsed.
+type docOptions struct {
+	parser    parser.Parser
+	wikiLinks bool // extract wiki-style links
+	hugo      bool // extract Hugo ref and relref shortcodes
+	jekyll    bool // extract Jekyll link and post_url tags
+
+	frontMatterKeys []string // front matter keys holding links
+
+	slugger    func(text string) string // if nil, slugify is used
+	extractors []LinkExtractor
+}
+
+// slug returns the anchor of the header with the plain text, decoded the
+// same way link fragments are.
+func (opts *docOptions) slug(text string) string {
+	if opts.slugger == nil {
+		return slugify(text)
+	}
+	s := opts.slugger(text)
+	if strings.Contains(s, "%") {
+		if u, err := url.PathUnescape(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+func (c *Checker) docOptions() *docOptions {
+	opts := &docOptions{
+		parser:          mdparser,
+		hugo:            c.HugoShortcodes,
+		jekyll:          c.JekyllTags,
+		frontMatterKeys: c.FrontMatterLinks,
+		slugger:         c.Slugger,
+		extractors:      c.Extractors,
+	}
+	if c.WikiLinks != WikiLinksDisabled {
+		opts.parser, opts.wikiLinks = wikiParser, true
+	}
+	if c.Dialect != DialectDefault || len(c.Extensions) != 0 || len(c.ParserOptions) != 0 {
+		opts.parser = newParser(c.Dialect, opts.wikiLinks, c.Extensions, c.ParserOptions)
+	}
+	return opts
+}
+
 type docDetails struct {
-	links   []LinkInfo          // non-external links
-	anchors map[string]struct{} // header slugs
+	links  []LinkInfo  // non-external links
+	wiki   []LinkInfo  // wiki-style links
+	hugo   []LinkInfo  // Hugo ref and relref shortcodes
+	jekyll []jekyllTag // Jekyll link and post_url tags
+
+	rstRefs []LinkInfo // reStructuredText :ref: roles, see checkRSTRefs
+	labels  []string   // reStructuredText labels, see rstLabels
+
+	undeclared []LinkInfo // Go doc links to undeclared identifiers, see parseGoFile
+
+	unusedRefs   []LinkInfo // unused link reference definitions, see unusedReferences
+	danglingRefs []LinkInfo // reference-style links without definitions, see danglingReferences
+	empty        []LinkInfo // links with empty destinations or just “#”
+
+	frontMatter map[string]any      // parsed YAML or TOML front matter
+	external    []LinkInfo          // absolute http(s) links
+	anchors     map[string]struct{} // header slugs and html element ids
+
+	all        []LinkInfo // all links in the document order, see DocInfo
+	anchorList []Anchor   // anchors in the document order
 }
 
-func extractDocDetails(body []byte) (*docDetails, error) {
-	// nodeContext returns numbers of the first and the last lines of the link
-	// context: block element that contains it, usually paragraph
-	nodeContext := func(n ast.Node) (int, int) {
+// extractDocDetails parses markdown document body; if opts is nil, defaults
+// are used.
+func extractDocDetails(body []byte, opts *docOptions) (*docDetails, error) {
+	if opts == nil {
+		opts = &docOptions{parser: mdparser}
+	}
+	fm := findFrontMatter(body)
+	// blockSpan returns byte offsets of the start and the end of the link
+	// context: block element that contains node n, usually paragraph; or
+	// zeroes if there's no such block
+	blockSpan := func(n ast.Node) (int, int) {
 		// only block type nodes have usable Lines() method, so if node is not
 		// a block type, find its first block ancestor
 		for n.Type() != ast.TypeBlock {
@@ -194,89 +1457,314 @@ func extractDocDetails(body []byte) (*docDetails, error) {
 		if stop == 0 || start == stop {
 			return 0, 0
 		}
+		return start, stop
+	}
+	// nodeContext returns numbers of the first and the last lines of the link
+	// context: block element that contains it, usually paragraph
+	nodeContext := func(n ast.Node) (int, int) {
+		start, stop := blockSpan(n)
+		if stop == 0 {
+			return 0, 0
+		}
 		startLine := 1 + bytes.Count(body[:start], []byte{'\n'})
 		endLine := startLine + bytes.Count(body[start:stop], []byte{'\n'})
 		return startLine, endLine
 	}
+	// lastOffset is the end of the last link destination found in the body;
+	// destinations of nodes without labels, like autolinks or html, are
+	// searched after it
+	var lastOffset int
+	// linkOffset returns byte offset in body where the link destination raw
+	// of node n starts; if it can't be found, the offset of the link itself
+	// is returned, or -1 if it's not known either
+	linkOffset := func(n ast.Node, raw string) int {
+		start, end := blockSpan(n)
+		if h, ok := n.(*ast.RawHTML); ok && h.Segments.Len() != 0 {
+			start, end = h.Segments.At(0).Start, h.Segments.At(h.Segments.Len()-1).Stop
+		}
+		label := -1 // offset of the “[” or “![” starting the link
+		if k := n.Kind(); k == ast.KindLink || k == ast.KindImage {
+			if i := firstTextOffset(n); i > 0 {
+				label = bytes.LastIndexByte(body[:i], '[')
+				if label > 0 && k == ast.KindImage && body[label-1] == '!' {
+					label--
+				}
+			}
+		}
+		from := start
+		if label >= 0 {
+			from = label
+		} else if lastOffset > from {
+			from = lastOffset
+		}
+		if raw != "" {
+			i := -1
+			if from < end {
+				if i = findLink(body, from, end, raw, nil); i < 0 {
+					if j := bytes.Index(body[from:end], []byte(raw)); j >= 0 {
+						i = from + j
+					}
+				}
+			}
+			if i >= 0 {
+				lastOffset = i + len(raw)
+				return i
+			}
+			// reference-style links have destinations in definitions
+			// elsewhere
+			if i = findLink(body, 0, len(body), raw, nil); i >= 0 {
+				return i
+			}
+		}
+		if label >= 0 {
+			return label
+		}
+		if end == 0 {
+			return -1
+		}
+		return start
+	}
 
-	var localLinks []LinkInfo
+	var localLinks, externalLinks, wikiLinks, emptyLinks, allLinks []LinkInfo
 	var anchors map[string]struct{}
+	var anchorList []Anchor
 
-	// localLink parses s and returns *url.URL only if the link is local
-	// (schema-less and domain-less link)
-	localLink := func(s string) *url.URL {
-		if s == "" {
-			return nil
+	if fm != nil {
+		for _, key := range opts.frontMatterKeys {
+			for _, raw := range fm.lookup(key) {
+				if u := localLink(raw); u != nil {
+					l := LinkInfo{Raw: raw, Path: u.Path, Fragment: u.Fragment}
+					l.setOffset(body, fm.offset(body, raw))
+					l.LineStart, l.LineEnd = l.Line, l.Line
+					localLinks = append(localLinks, l)
+				}
+			}
 		}
-		u, err := url.Parse(s)
-		if err != nil || u.Scheme != "" || u.Host != "" {
-			return nil
+	}
+	body = blankFrontMatter(body, fm)
+	// addLinkInfo records link l with Raw and position fields set
+	addLinkInfo := func(l LinkInfo) {
+		if l.Raw == "#" { // doesn't point anywhere, but is not empty
+			allLinks = append(allLinks, l)
+			emptyLinks = append(emptyLinks, l)
+			return
 		}
-		if u.Path == "" && u.Fragment == "" {
-			return nil
+		u := localLink(l.Raw)
+		if u != nil {
+			l.Path, l.Fragment = u.Path, u.Fragment
+		}
+		allLinks = append(allLinks, l)
+		switch {
+		case u != nil:
+			localLinks = append(localLinks, l)
+		case isExternalLink(l.Raw):
+			externalLinks = append(externalLinks, l)
+		}
+	}
+	// addLink records link target raw, as seen in the document body, found
+	// in node n
+	addLink := func(n ast.Node, raw string) {
+		if raw == "" {
+			return
+		}
+		l1, l2 := nodeContext(n)
+		l := LinkInfo{Raw: raw, LineStart: l1, LineEnd: l2}
+		l.setOffset(body, linkOffset(n, raw))
+		addLinkInfo(l)
+	}
+	// addEmptyLink records link or image node n without destination
+	addEmptyLink := func(n ast.Node) {
+		l1, l2 := nodeContext(n)
+		l := LinkInfo{LineStart: l1, LineEnd: l2}
+		l.setOffset(body, linkOffset(n, ""))
+		allLinks = append(allLinks, l)
+		emptyLinks = append(emptyLinks, l)
+	}
+	// addAnchor records anchor id found at the byte offset off, unless it's
+	// already known, and reports whether it was added; level and text are
+	// set for header anchors
+	addAnchor := func(id string, off, level int, text string) bool {
+		if anchors == nil {
+			anchors = make(map[string]struct{})
 		}
-		return u
+		if _, ok := anchors[id]; ok {
+			return false
+		}
+		anchors[id] = struct{}{}
+		a := Anchor{ID: id, Level: level, Text: text, Offset: off}
+		a.Line, a.Column = textPosition(body, off)
+		anchorList = append(anchorList, a)
+		return true
 	}
 	fn := func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
-		var u *url.URL
-		var raw string // link target as seen in the document body
 		switch n.Kind() {
 		case ast.KindHeading:
 			if n, ok := n.(*ast.Heading); ok {
-				if name := slugify(nodeText(n, body)); name != "" {
-					if anchors == nil {
-						anchors = make(map[string]struct{})
+				text := nodeText(n, body)
+				name := opts.slug(text)
+				// explicit ids, like “# Header {#custom-id}”, take
+				// precedence over ones generated from the header text
+				if v, ok := n.AttributeString("id"); ok {
+					if id, ok := v.([]byte); ok && len(id) != 0 {
+						name = string(id)
 					}
+				}
+				if name != "" {
+					start, _ := blockSpan(n)
+					start = bytes.LastIndexByte(body[:start], '\n') + 1 // header line
 					for i := 0; i < 100; i++ {
-						var cand string
-						if i == 0 {
-							cand = name
-						} else {
+						cand := name
+						if i != 0 {
 							cand = fmt.Sprintf("%s-%d", name, i)
 						}
-						if _, ok := anchors[cand]; !ok {
-							anchors[cand] = struct{}{}
+						if addAnchor(cand, start, n.Level, text) {
 							break
 						}
 					}
 				}
 			}
+		case ast.KindHTMLBlock, ast.KindRawHTML:
+			src := rawHTML(n, body)
+			start, end := blockSpan(n)
+			if h, ok := n.(*ast.RawHTML); ok && h.Segments.Len() != 0 {
+				start, end = h.Segments.At(0).Start, h.Segments.At(h.Segments.Len()-1).Stop
+			}
+			for _, id := range htmlAnchors(src) {
+				off := start
+				if i := bytes.Index(body[start:end], []byte(id)); i >= 0 {
+					off += i
+				}
+				addAnchor(id, off, 0, "")
+			}
+			for _, raw := range htmlLinks(src) {
+				addLink(n, raw)
+			}
 		case ast.KindAutoLink:
 			if l, ok := n.(*ast.AutoLink); ok && l.AutoLinkType == ast.AutoLinkURL {
-				raw = string(l.URL(body))
-				u = localLink(raw)
+				addLink(n, string(l.URL(body)))
 			}
 		case ast.KindLink:
 			if l, ok := n.(*ast.Link); ok {
-				raw = string(l.Destination)
-				u = localLink(raw)
+				if len(l.Destination) == 0 {
+					addEmptyLink(n)
+				}
+				addLink(n, string(l.Destination))
 			}
 		case ast.KindImage:
 			if l, ok := n.(*ast.Image); ok {
-				raw = string(l.Destination)
-				u = localLink(raw)
-			}
-		}
-		if u != nil && raw != "" {
-			l1, l2 := nodeContext(n)
-			localLinks = append(localLinks, LinkInfo{
-				Raw:       raw,
-				Path:      u.Path,
-				Fragment:  u.Fragment,
-				LineStart: l1,
-				LineEnd:   l2,
-			})
+				if len(l.Destination) == 0 {
+					addEmptyLink(n)
+				}
+				addLink(n, string(l.Destination))
+			}
+		case kindWikiLink:
+			if l, ok := n.(*wikiLink); ok && opts.wikiLinks {
+				l1, l2 := nodeContext(n)
+				p, frag := splitWikiTarget(string(l.Target))
+				li := LinkInfo{
+					Raw:       string(l.Raw),
+					Path:      p,
+					Fragment:  frag,
+					LineStart: l1,
+					LineEnd:   l2,
+				}
+				li.setOffset(body, l.Offset)
+				wikiLinks = append(wikiLinks, li)
+			}
 		}
 		return ast.WalkContinue, nil
 	}
-	node := mdparser.Parse(text.NewReader(body))
+	pc := newRefContext()
+	node := opts.parser.Parse(text.NewReader(body), parser.WithContext(pc))
 	if err := ast.Walk(node, fn); err != nil {
 		return nil, err
 	}
-	return &docDetails{anchors: anchors, links: localLinks}, nil
+	for _, extract := range opts.extractors {
+		links, err := extract(body, node)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range links {
+			if l, ok := extractedLink(body, x); ok {
+				addLinkInfo(l)
+			}
+		}
+	}
+	if len(opts.extractors) != 0 {
+		// keep links in the document order
+		for _, links := range [][]LinkInfo{localLinks, externalLinks, emptyLinks, allLinks} {
+			sort.SliceStable(links, func(i, j int) bool { return links[i].Offset < links[j].Offset })
+		}
+	}
+	var hugoRefs []LinkInfo
+	if opts.hugo {
+		hugoRefs = extractHugoRefs(body)
+	}
+	var jekyllTags []jekyllTag
+	if opts.jekyll {
+		jekyllTags = extractJekyllTags(body)
+	}
+	var fmValues map[string]any
+	if fm != nil {
+		fmValues = fm.values
+	}
+	code := codeRanges(node)
+	return &docDetails{
+		frontMatter: fmValues,
+		anchors:     anchors,
+		links:       localLinks,
+		external:    externalLinks,
+		wiki:        wikiLinks,
+		hugo:        hugoRefs,
+		jekyll:      jekyllTags,
+		unusedRefs:  unusedReferences(body, pc, code),
+
+		danglingRefs: danglingReferences(body, pc, code),
+		empty:        emptyLinks,
+		all:          allLinks,
+		anchorList:   anchorList,
+	}, nil
+}
+
+// localLink parses s and returns *url.URL only if the link is local
+// (schema-less and domain-less link). Links with malformed percent-encoding,
+// like “100%.md”, are taken literally.
+func localLink(s string) *url.URL {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		if schemeRe.MatchString(s) || strings.HasPrefix(s, "//") {
+			return nil
+		}
+		p, frag, _ := strings.Cut(s, "#")
+		p, _, _ = strings.Cut(p, "?")
+		if f, err := url.PathUnescape(frag); err == nil {
+			frag = f
+		}
+		u = &url.URL{Path: p, Fragment: frag}
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return nil
+	}
+	if u.Path == "" && u.Fragment == "" {
+		return nil
+	}
+	return u
+}
+
+var schemeRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// rawLinkPath returns the path part of the link as it's written, without
+// percent-decoding.
+func rawLinkPath(raw string) string {
+	p, _, _ := strings.Cut(raw, "#")
+	p, _, _ = strings.Cut(p, "?")
+	return p
 }
 
 // BrokenLinksError is an error type returned by this package functions to
@@ -301,53 +1789,335 @@ func (e *BrokenLinksError) Error() string { return "broken links found" }
 type BrokenLink struct {
 	File string // file path, relative to directory/filesystem scanned; uses '/' as a separator
 	Link LinkInfo
+
+	// Suggestion, if not empty, is a replacement for Link.Raw that likely
+	// fixes the link, like a path to the file with the same name found
+	// elsewhere.
+	Suggestion string
+
 	kind violationKind
 }
 
 func (b BrokenLink) String() string {
+	file := b.File
+	if b.Link.Cell != 0 {
+		file = fmt.Sprintf("%s (cell %d)", b.File, b.Link.Cell)
+	}
+	return file + ": " + b.Message()
+}
+
+// Message returns the description of the broken link, like String does, but
+// without the file path and the notebook cell, for reports showing them
+// separately.
+func (b BrokenLink) Message() string {
+	if b.Suggestion != "" {
+		return fmt.Sprintf("%s; did you mean %q?", b.message(), b.Suggestion)
+	}
+	return b.message()
+}
+
+func (b BrokenLink) message() string {
 	switch b.kind {
 	case kindBrokenInternalAnchor:
-		return fmt.Sprintf("%s: link %q points to a non-existing local slug", b.File, b.Link.Raw)
+		return fmt.Sprintf("link %q points to a non-existing local slug", b.Link.Raw)
 	case kindBrokenExternalAnchor:
-		return fmt.Sprintf("%s: link %q points to a non-existing slug", b.File, b.Link.Raw)
+		return fmt.Sprintf("link %q points to a non-existing slug", b.Link.Raw)
+	case kindDeadExternal:
+		return fmt.Sprintf("link %q points to an unreachable remote resource", b.Link.Raw)
+	case kindNavMissing:
+		return fmt.Sprintf("nav entry %q points to a non-existing file", b.Link.Raw)
+	case kindNotInNav:
+		return "document is not listed in the nav"
+	case kindSummaryMissing:
+		return fmt.Sprintf("chapter %q points to a non-existing file", b.Link.Raw)
+	case kindNotInSummary:
+		return "document is not listed in the summary"
+	case kindViaRedirect:
+		return fmt.Sprintf("link %q points to a redirected location", b.Link.Raw)
+	case kindUnencodedSpace:
+		return fmt.Sprintf("link %q has unencoded spaces", b.Link.Raw)
+	case kindCaseMismatch:
+		return fmt.Sprintf("link %q does not match the case of the file name", b.Link.Raw)
+	case kindUnusedReference:
+		return fmt.Sprintf("reference definition [%s] is never used", b.Link.Raw)
+	case kindMissingReference:
+		return fmt.Sprintf("reference [%s] has no definition", b.Link.Raw)
+	case kindEmptyLink:
+		return "link has an empty destination"
+	case kindPlaceholderLink:
+		return fmt.Sprintf("link %q is a placeholder", b.Link.Raw)
+	case kindSelfLink:
+		return fmt.Sprintf("link %q points to the document itself", b.Link.Raw)
+	case kindRedundantPath:
+		return fmt.Sprintf("link %q has redundant path segments", b.Link.Raw)
+	case kindMissingLabel:
+		return fmt.Sprintf("label %q is not defined in any document", b.Link.Raw)
+	case kindUndeclaredIdentifier:
+		return fmt.Sprintf("doc link [%s] refers to an undeclared identifier", b.Link.Raw)
+	case kindLineOutOfRange:
+		return fmt.Sprintf("link %q points to non-existing lines", b.Link.Raw)
+	case kindQueryString, kindForbiddenQuery:
+		return fmt.Sprintf("link %q has a query string", b.Link.Raw)
+	case kindInvalidEmail:
+		return fmt.Sprintf("link %q has an invalid email address", b.Link.Raw)
+	case kindEmptyMailto:
+		return fmt.Sprintf("link %q has no email addresses", b.Link.Raw)
+	case kindDuplicateEmail:
+		return fmt.Sprintf("link %q has duplicate email addresses", b.Link.Raw)
+	case kindAbsoluteLink:
+		return fmt.Sprintf("link %q should be relative", b.Link.Raw)
+	case kindRelativeLink:
+		return fmt.Sprintf("link %q should be root-absolute", b.Link.Raw)
+	case kindOutsideRoot:
+		return fmt.Sprintf("link %q points outside of the scanned directory", b.Link.Raw)
 	}
-	return fmt.Sprintf("%s: link %q points to a non-existing file", b.File, b.Link.Raw)
+	return fmt.Sprintf("link %q points to a non-existing file", b.Link.Raw)
 }
 
+// Reason returns human-readable description of why the link is considered
+// broken.
 func (b BrokenLink) Reason() string { return b.kind.String() }
 
+// Kind returns a short stable identifier of the violation kind, suitable for
+// machine-readable reports, e.g. “missing-file”.
+func (b BrokenLink) Kind() string { return b.kind.code() }
+
+// IsWarning reports whether b describes a link that still works, but is worth
+// fixing, like a link to the redirected location.
+func (b BrokenLink) IsWarning() bool { return b.kind.isWarning() }
+
 type violationKind byte
 
 const (
-	kindFileNotExists = iota
+	kindFileNotExists violationKind = iota
 	kindBrokenInternalAnchor
 	kindBrokenExternalAnchor
+	kindDeadExternal
+	kindNavMissing
+	kindNotInNav
+	kindSummaryMissing
+	kindNotInSummary
+	kindViaRedirect
+	kindUnencodedSpace
+	kindCaseMismatch
+	kindUnusedReference
+	kindMissingReference
+	kindEmptyLink
+	kindPlaceholderLink
+	kindSelfLink
+	kindRedundantPath
+	kindMissingLabel
+	kindUndeclaredIdentifier
+	kindLineOutOfRange
+	kindQueryString
+	kindForbiddenQuery
+	kindInvalidEmail
+	kindEmptyMailto
+	kindDuplicateEmail
+	kindAbsoluteLink
+	kindRelativeLink
+	kindOutsideRoot
 )
 
+// isWarning reports whether violations of this kind don't make links
+// unusable, but are still worth fixing.
+func (v violationKind) isWarning() bool {
+	switch v {
+	case kindViaRedirect, kindUnencodedSpace, kindUnusedReference, kindSelfLink, kindRedundantPath,
+		kindQueryString, kindInvalidEmail, kindEmptyMailto, kindDuplicateEmail,
+		kindAbsoluteLink, kindRelativeLink:
+		return true
+	}
+	return false
+}
+
 func (v violationKind) String() string {
 	switch v {
 	case kindBrokenInternalAnchor:
 		return "link points to a non-existing local slug"
 	case kindBrokenExternalAnchor:
 		return "link points to a non-existing slug"
+	case kindDeadExternal:
+		return "link points to an unreachable remote resource"
+	case kindNavMissing:
+		return "nav entry points to a non-existing file"
+	case kindNotInNav:
+		return "document is not listed in the nav"
+	case kindSummaryMissing:
+		return "chapter points to a non-existing file"
+	case kindNotInSummary:
+		return "document is not listed in the summary"
+	case kindViaRedirect:
+		return "link points to a redirected location"
+	case kindUnencodedSpace:
+		return "link has unencoded spaces"
+	case kindCaseMismatch:
+		return "link does not match the case of the file name"
+	case kindUnusedReference:
+		return "reference definition is never used"
+	case kindMissingReference:
+		return "reference has no definition"
+	case kindEmptyLink:
+		return "link has an empty destination"
+	case kindPlaceholderLink:
+		return "link is a placeholder"
+	case kindSelfLink:
+		return "link points to the document itself"
+	case kindRedundantPath:
+		return "link has redundant path segments"
+	case kindMissingLabel:
+		return "label is not defined in any document"
+	case kindUndeclaredIdentifier:
+		return "doc link refers to an undeclared identifier"
+	case kindLineOutOfRange:
+		return "link points to non-existing lines"
+	case kindQueryString, kindForbiddenQuery:
+		return "link has a query string"
+	case kindInvalidEmail:
+		return "link has an invalid email address"
+	case kindEmptyMailto:
+		return "link has no email addresses"
+	case kindDuplicateEmail:
+		return "link has duplicate email addresses"
+	case kindAbsoluteLink:
+		return "link should be relative"
+	case kindRelativeLink:
+		return "link should be root-absolute"
+	case kindOutsideRoot:
+		return "link points outside of the scanned directory"
 	}
 	return "link points to a non-existing file"
 }
 
+func (v violationKind) code() string {
+	switch v {
+	case kindBrokenInternalAnchor:
+		return "missing-local-anchor"
+	case kindBrokenExternalAnchor:
+		return "missing-anchor"
+	case kindDeadExternal:
+		return "unreachable-url"
+	case kindNavMissing:
+		return "missing-nav-target"
+	case kindNotInNav:
+		return "not-in-nav"
+	case kindSummaryMissing:
+		return "missing-chapter"
+	case kindNotInSummary:
+		return "not-in-summary"
+	case kindViaRedirect:
+		return "via-redirect"
+	case kindUnencodedSpace:
+		return "unencoded-space"
+	case kindCaseMismatch:
+		return "case-mismatch"
+	case kindUnusedReference:
+		return "unused-reference"
+	case kindMissingReference:
+		return "missing-reference"
+	case kindEmptyLink:
+		return "empty-link"
+	case kindPlaceholderLink:
+		return "placeholder-link"
+	case kindSelfLink:
+		return "self-link"
+	case kindRedundantPath:
+		return "redundant-path"
+	case kindMissingLabel:
+		return "missing-label"
+	case kindUndeclaredIdentifier:
+		return "undeclared-identifier"
+	case kindLineOutOfRange:
+		return "line-out-of-range"
+	case kindQueryString:
+		return "query-string"
+	case kindForbiddenQuery:
+		return "forbidden-query"
+	case kindInvalidEmail:
+		return "invalid-email"
+	case kindEmptyMailto:
+		return "empty-mailto"
+	case kindDuplicateEmail:
+		return "duplicate-email"
+	case kindAbsoluteLink:
+		return "absolute-link"
+	case kindRelativeLink:
+		return "relative-link"
+	case kindOutsideRoot:
+		return "outside-root"
+	}
+	return "missing-file"
+}
+
 // LinkInfo describes markdown link
 type LinkInfo struct {
 	Raw       string // as seen in the source, usually “some/path#fragment”
 	Path      string // only the path part of the link
 	Fragment  string // only the fragment part of the link, without '#'
+	Query     string // only the query part of the link, without '?'
 	LineStart int    // number of the first line of the context (usually paragraph)
 	LineEnd   int    // number of the last line of the context (usually paragraph)
+
+	// Line and Column are the 1-based line number and byte column where Raw
+	// starts in the source; Offset is the 0-based byte offset of the same
+	// position. If Raw can't be found as is, like when it has escaped
+	// characters, they point to the start of the link itself. Line is 0 if
+	// the position is unknown.
+	Line, Column, Offset int
+
+	// Cell is the 1-based index of the Jupyter notebook cell the link is
+	// found in, counting all cells; 0 for other documents. Positions of
+	// links in notebooks are relative to the source of their cells.
+	Cell int
 }
 
-var mdparser = parser.NewParser(
-	parser.WithBlockParsers(parser.DefaultBlockParsers()...),
-	parser.WithInlineParsers(parser.DefaultInlineParsers()...),
-	parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
-)
+// linkBefore reports whether the link a precedes the link b in their
+// document.
+func linkBefore(a, b LinkInfo) bool {
+	if a.Cell != b.Cell {
+		return a.Cell < b.Cell
+	}
+	return a.Offset < b.Offset
+}
+
+// setOffset sets Offset, Line, and Column of l to describe the byte offset
+// off of body. It does nothing if off is outside of body.
+func (l *LinkInfo) setOffset(body []byte, off int) {
+	if off < 0 || off > len(body) {
+		return
+	}
+	l.Offset = off
+	l.Line, l.Column = textPosition(body, off)
+}
+
+// textPosition returns 1-based line number and byte column of the byte
+// offset off of body.
+func textPosition(body []byte, off int) (line, column int) {
+	lineStart := bytes.LastIndexByte(body[:off], '\n') + 1
+	return 1 + bytes.Count(body[:off], []byte{'\n'}), 1 + off - lineStart
+}
+
+// firstTextOffset returns the offset of the first text segment among
+// descendants of node n, or -1 if there's none.
+func firstTextOffset(n ast.Node) int {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			return t.Segment.Start
+		}
+		if i := firstTextOffset(c); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// mdparser parses GitHub Flavored Markdown (tables, strikethrough, task lists,
+// autolinks) with footnotes, so that links in table cells and footnote bodies
+// are seen too. It also recognizes explicit header ids (“{#custom-id}”).
+var mdparser = newParser(DialectDefault, false, nil, nil)
+
+// wikiParser is like mdparser, but also recognizes wiki-style links.
+var wikiParser = newParser(DialectDefault, true, nil, nil)
 
 // nodeText walks node and extracts plain text from it and its descendants,
 // effectively removing all markdown syntax
@@ -370,18 +2140,3 @@ func nodeText(node ast.Node, src []byte) string {
 	}
 	return b.String()
 }
-
-func slugify(text string) string {
-	f := func(r rune) rune {
-		switch {
-		case unicode.IsLetter(r) || unicode.IsNumber(r):
-			return unicode.ToLower(r)
-		case unicode.IsSpace(r):
-			return '-'
-		case r == '-' || r == '_':
-			return r
-		}
-		return -1
-	}
-	return strings.Map(f, text)
-}
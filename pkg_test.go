@@ -1,13 +1,28 @@
 package mdlinks
 
 import (
+	"archive/tar"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 func Test_slugify(t *testing.T) {
@@ -23,13 +38,14 @@ func Test_slugify(t *testing.T) {
 		{`Header with & symbol`, `header-with--symbol`},
 		{`Punctuation,   and    repeating:  spaces`, `punctuation---and----repeating--spaces`},
 		{`_foo_bar`, `_foo_bar`},
+		{`Custom ID {#my-id}`, `my-id`},
 	}
 	var body []byte
 	for _, c := range testCases {
 		body = body[:0]
 		body = append([]byte("# "), c.text...)
 		body = append(body, "\n\nText\n"...)
-		d, err := extractDocDetails(body)
+		d, err := extractDocDetails(body, nil)
 		if err != nil {
 			t.Fatalf("extracting doc details for header %q: %v", c.text, err)
 		}
@@ -121,6 +137,9 @@ func test1CheckFS(t *testing.T) {
 			Fragment:  "hi",
 			LineStart: 3,
 			LineEnd:   4,
+			Line:      4,
+			Column:    13,
+			Offset:    55,
 		},
 	}
 
@@ -174,3 +193,2656 @@ func copyDirectory(dst, src string) error {
 	}
 	return fs.WalkDir(srcFS, ".", fn)
 }
+
+func TestCheckFS_external(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte("# Doc\n\n[ok](" + srv.URL + "/ok#frag), [dead](" + srv.URL + "/dead)\n\n<" + srv.URL + "/ok>\n")},
+	}
+	c := &Checker{
+		Matcher:       func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		CheckExternal: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != srv.URL+"/dead" {
+		t.Fatalf("unexpected broken links: %v", e.Links)
+	}
+	if got, want := e.Links[0].Reason(), "link points to an unreachable remote resource"; got != want {
+		t.Fatalf("got reason %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_ignoreLinks(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte("[a]({{site.url}}/page.md), [b](generated/api.md), [c](missing.md)\n")},
+	}
+	c := &Checker{
+		Matcher:     func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		IgnoreLinks: []string{`^\{\{`, `^generated/`},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "missing.md" {
+		t.Fatalf("unexpected broken links: %v", e.Links)
+	}
+	c.IgnoreLinks = []string{"("}
+	if err := c.CheckFS(fsys); err == nil || errors.As(err, &e) {
+		t.Fatalf("want invalid expression error, got %v", err)
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	t.Parallel()
+	match, err := MatchPatterns([]string{"docs/**/*.md", "*.markdown", "/README.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{
+		"docs/index.md":        true,
+		"docs/a/b/setup.md":    true,
+		"src/docs/index.md":    false,
+		"notes.md":             false,
+		"notes.markdown":       true,
+		"a/b/notes.markdown":   true,
+		"README.md":            true,
+		"pkg/README.md":        false,
+		"docs/a/notes.md.orig": false,
+	} {
+		got, err := match(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("path %q: got %v, want %v", p, got, want)
+		}
+	}
+	if _, err := MatchPatterns([]string{"docs/[a.md"}); err == nil {
+		t.Error("bad pattern: want error")
+	}
+}
+
+func TestExcludePatterns(t *testing.T) {
+	t.Parallel()
+	exclude, err := ExcludePatterns([]string{
+		"# comment",
+		"vendor/",
+		"node_modules",
+		"/generated",
+		"docs/**/draft-*.md",
+		"*.tmp.md",
+		"!keep.tmp.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"vendor", true, true},
+		{"a/vendor", true, true},
+		{"vendor", false, false},
+		{"a/node_modules", true, true},
+		{"generated", true, true},
+		{"a/generated", true, false},
+		{"docs/draft-1.md", false, true},
+		{"docs/a/b/draft-1.md", false, true},
+		{"docs/a/final.md", false, false},
+		{"a/x.tmp.md", false, true},
+		{"a/keep.tmp.md", false, false},
+		{"README.md", false, false},
+	}
+	for _, tc := range testCases {
+		got, err := exclude(tc.path, tc.isDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("path %q (dir: %v): got %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"doc.md":            &fstest.MapFile{Data: []byte("[ok](doc.md)\n")},
+		"vendor/lib/doc.md": &fstest.MapFile{Data: []byte("[broken](missing.md)\n")},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Exclude: exclude,
+	}
+	if err := c.CheckFS(fsys); err != nil {
+		t.Fatalf("want no error, got: %v", err)
+	}
+}
+
+func TestCheckFS_respectGitignore(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		".gitignore":           &fstest.MapFile{Data: []byte("build/\n")},
+		"doc.md":               &fstest.MapFile{Data: []byte("[ok](doc.md)\n")},
+		"build/out.md":         &fstest.MapFile{Data: []byte("[broken](missing.md)\n")},
+		"sub/.gitignore":       &fstest.MapFile{Data: []byte("*.gen.md\n")},
+		"sub/api.gen.md":       &fstest.MapFile{Data: []byte("[broken](missing.md)\n")},
+		"sub/guide.md":         &fstest.MapFile{Data: []byte("[broken](missing.md)\n")},
+		"other/api.gen.md":     &fstest.MapFile{Data: []byte("[broken](missing.md)\n")},
+		"sub/deeper/readme.md": &fstest.MapFile{Data: []byte("[ok](../guide.md)\n")},
+	}
+	c := &Checker{
+		Matcher:          func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		RespectGitignore: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.File)
+	}
+	if want := []string{"other/api.gen.md", "sub/guide.md"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links in %q, want %q", got, want)
+	}
+}
+
+func Test_extractDocDetails_gfm(t *testing.T) {
+	t.Parallel()
+	const doc = `# Doc
+
+| Name | Link |
+|------|------|
+| one  | [one](one.md) |
+
+Text with a footnote[^1].
+
+[^1]: See [two](two.md#intro).
+`
+	d, err := extractDocDetails([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range d.links {
+		got = append(got, fmt.Sprintf("%s:%d", l.Raw, l.LineStart))
+	}
+	if want := []string{"one.md:5", "two.md#intro:9"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got links %q, want %q", got, want)
+	}
+}
+
+func Test_extractDocDetails_htmlAnchors(t *testing.T) {
+	t.Parallel()
+	const doc = `# Doc
+
+<a id="install"></a>
+
+Some <a name="inline">inline</a> anchor and <span id='span-id'>span</span>.
+
+<div id="block">
+Text.
+</div>
+`
+	d, err := extractDocDetails([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"doc", "install", "inline", "span-id", "block"} {
+		if _, ok := d.anchors[id]; !ok {
+			t.Errorf("anchor %q not found, got anchors: %v", id, d.anchors)
+		}
+	}
+}
+
+func Test_extractDocDetails_htmlLinks(t *testing.T) {
+	t.Parallel()
+	const doc = `# Doc
+
+<p align="center">
+  <img src="logo.png" srcset="logo.png 1x, logo@2x.png 2x" alt="logo">
+</p>
+
+See <a href="other.md#intro">other</a> and <a href="https://example.com/">site</a>.
+`
+	d, err := extractDocDetails([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range d.links {
+		got = append(got, fmt.Sprintf("%s:%d", l.Raw, l.LineStart))
+	}
+	if want := []string{"logo.png:3", "logo.png:3", "logo@2x.png:3", "other.md#intro:7"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got links %q, want %q", got, want)
+	}
+	if len(d.external) != 1 || d.external[0].Raw != "https://example.com/" {
+		t.Fatalf("unexpected external links: %v", d.external)
+	}
+}
+
+func TestCheckFS_htmlTargets(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":    &fstest.MapFile{Data: []byte("[a](page.html#setup), [b](page.html#old), [c](page.html)\n")},
+		"page.html": &fstest.MapFile{Data: []byte("<html><body><h2 id=\"setup\">Setup</h2><a name=\"top\"></a></body></html>")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "page.html#old" {
+		t.Fatalf("unexpected broken links: %v", e.Links)
+	}
+}
+
+func TestCheckFS_wikiLinks(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"index.md": &fstest.MapFile{Data: []byte(`# Index
+
+See [[Page]], [[Page#Second Part|details]], [[Page#Missing]], [[Nowhere]],
+[[#Index]], [[#Other]], and ` + "`[[Code]]`" + `.
+
+![[image.png|100]] ![[missing.png]] ![[Page#Second Part]] ![[Page#Gone]] ![alt](image.png)
+`)},
+		"image.png":     &fstest.MapFile{},
+		"notes/Page.md": &fstest.MapFile{Data: []byte("# Page\n\n## Second Part\n")},
+	}
+	testCases := []struct {
+		mode WikiLinkMode
+		want []string
+	}{
+		{WikiLinksDisabled, nil},
+		{WikiLinksShortestPath, []string{
+			`index.md: link "[[Page#Missing]]" points to a non-existing slug`,
+			`index.md: link "[[Nowhere]]" points to a non-existing file`,
+			`index.md: link "[[#Other]]" points to a non-existing local slug`,
+			`index.md: link "![[missing.png]]" points to a non-existing file`,
+			`index.md: link "![[Page#Gone]]" points to a non-existing slug`,
+		}},
+		{WikiLinksSameDir, []string{
+			`index.md: link "[[Page]]" points to a non-existing file`,
+			`index.md: link "[[Page#Second Part|details]]" points to a non-existing file`,
+			`index.md: link "[[Page#Missing]]" points to a non-existing file`,
+			`index.md: link "[[Nowhere]]" points to a non-existing file`,
+			`index.md: link "[[#Other]]" points to a non-existing local slug`,
+			`index.md: link "![[missing.png]]" points to a non-existing file`,
+			`index.md: link "![[Page#Second Part]]" points to a non-existing file`,
+			`index.md: link "![[Page#Gone]]" points to a non-existing file`,
+		}},
+	}
+	for _, tc := range testCases {
+		c := &Checker{
+			Matcher:   func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			WikiLinks: tc.mode,
+		}
+		err := c.CheckFS(fsys)
+		var got []string
+		var e *BrokenLinksError
+		if errors.As(err, &e) {
+			for _, l := range e.Links {
+				got = append(got, l.String())
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("mode %v, got:\n%s\n\nwant:\n%s", tc.mode, strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestCheckFS_hugoShortcodes(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"posts/first.md": &fstest.MapFile{Data: []byte(`# First
+
+See [second]({{< ref "second.md#setup" >}}), [bundle]({{< relref "/bundle" >}}),
+[about]({{% ref path="about" lang="en" %}}), [self]({{< ref "#first" >}}).
+
+Broken: {{< ref "missing.md" >}} and {{< relref "second#nope" >}}.
+`)},
+		"posts/second.md":  &fstest.MapFile{Data: []byte("# Second\n\n## Setup\n")},
+		"bundle/index.md":  &fstest.MapFile{Data: []byte("# Bundle\n")},
+		"about/_index.md":  &fstest.MapFile{Data: []byte("# About\n")},
+		"posts/ignored.md": &fstest.MapFile{Data: []byte("No refs {{</* ref \"missing.md\" */>}} here.\n")},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		HugoShortcodes: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.String())
+	}
+	want := []string{
+		`posts/first.md: link "{{< ref \"missing.md\" >}}" points to a non-existing file`,
+		`posts/first.md: link "{{< relref \"second#nope\" >}}" points to a non-existing slug`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_jekyllTags(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"index.md": &fstest.MapFile{Data: []byte(`# Index
+
+[About]({% link about/index.md %}), [post]({% post_url 2023-01-01-hello %}),
+[nested]({%- post_url news/2023-02-01-update -%}), [var]({% link {{ page.x }} %}).
+
+Broken: {% link missing.md %} and {% post_url 2020-01-01-gone %}.
+`)},
+		"about/index.md":                   &fstest.MapFile{Data: []byte("# About\n")},
+		"_posts/2023-01-01-hello.md":       &fstest.MapFile{Data: []byte("# Hello\n")},
+		"_posts/news/2023-02-01-update.md": &fstest.MapFile{Data: []byte("# Update\n")},
+	}
+	c := &Checker{
+		Matcher:    func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		JekyllTags: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.String())
+	}
+	want := []string{
+		`index.md: link "{% link missing.md %}" points to a non-existing file`,
+		`index.md: link "{% post_url 2020-01-01-gone %}" points to a non-existing file`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_mkdocs(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"mkdocs.yml": &fstest.MapFile{Data: []byte(`site_name: Test
+markdown_extensions:
+  - pymdownx.emoji:
+      emoji_index: !!python/name:material.extensions.emoji.twemoji
+nav:
+  - index.md
+  - 'User Guide':
+      - 'Writing': user-guide/writing.md
+      - 'Missing': user-guide/missing.md
+  - 'Bug Tracker': https://example.com/
+`)},
+		"docs/index.md":              &fstest.MapFile{Data: []byte("# Index\n")},
+		"docs/user-guide/writing.md": &fstest.MapFile{Data: []byte("# Writing\n")},
+		"docs/orphan.md":             &fstest.MapFile{Data: []byte("# Orphan\n")},
+		"README.md":                  &fstest.MapFile{Data: []byte("# Readme\n")},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		MkDocsConfig:   "mkdocs.yml",
+		MkDocsUnlisted: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.String())
+	}
+	want := []string{
+		`mkdocs.yml: nav entry "user-guide/missing.md" points to a non-existing file`,
+		`docs/orphan.md: document is not listed in the nav`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if l := e.Links[0].Link; l.LineStart != 9 {
+		t.Fatalf("got nav entry line %d, want 9", l.LineStart)
+	}
+}
+
+func TestCheckFS_mdbook(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"src/SUMMARY.md": &fstest.MapFile{Data: []byte(`# Summary
+
+[Introduction](intro.md)
+
+- [Chapter 1](chapter_1.md)
+  - [Section](chapter_1/section.md)
+- [Draft]()
+`)},
+		"src/intro.md":     &fstest.MapFile{Data: []byte("# Intro\n")},
+		"src/chapter_1.md": &fstest.MapFile{Data: []byte("# Chapter 1\n")},
+		"src/orphan.md":    &fstest.MapFile{Data: []byte("# Orphan\n")},
+		"README.md":        &fstest.MapFile{Data: []byte("# Readme\n")},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		MdBookSummary:  "src/SUMMARY.md",
+		MdBookUnlisted: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.String())
+	}
+	want := []string{
+		`src/SUMMARY.md: chapter "chapter_1/section.md" points to a non-existing file`,
+		`src/orphan.md: document is not listed in the summary`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_docusaurus(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/intro.md": &fstest.MapFile{Data: []byte(`---
+title: Intro
+---
+# Intro
+
+[a](./guide#setup), [b](./api), [c](/getting-started), [d](./renamed), [e](./missing).
+`)},
+		"docs/guide.mdx":    &fstest.MapFile{Data: []byte("# Guide\n\n## Setup\n")},
+		"docs/api/index.md": &fstest.MapFile{Data: []byte("# API\n")},
+		"docs/start.md":     &fstest.MapFile{Data: []byte("---\nslug: /getting-started\n---\n# Start\n")},
+		"docs/original.md":  &fstest.MapFile{Data: []byte("---\nid: renamed\n---\n# Renamed\n")},
+	}
+	c := &Checker{
+		Matcher:         func(s string) (bool, error) { return strings.HasPrefix(path.Ext(s), ".md"), nil },
+		DocusaurusLinks: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "./missing" {
+		t.Fatalf("unexpected broken links: %v", e.Links)
+	}
+}
+
+func Test_extractDocDetails_frontMatter(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name, doc string
+	}{
+		{"yaml", "---\ntitle: Front\ntags: [a, b]\n---\n# Doc\n\n[link](other.md)\n"},
+		{"toml", "+++\ntitle = \"Front\"\ntags = [\"a\", \"b\"]\n+++\n# Doc\n\n[link](other.md)\n"},
+	}
+	for _, tc := range testCases {
+		d, err := extractDocDetails([]byte(tc.doc), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.anchors) != 1 {
+			t.Errorf("%s: got anchors %v, want only “doc”", tc.name, d.anchors)
+		}
+		if len(d.links) != 1 || d.links[0].LineStart != 7 {
+			t.Errorf("%s: unexpected links: %+v", tc.name, d.links)
+		}
+		if got := d.frontMatter["title"]; got != "Front" {
+			t.Errorf("%s: got front matter title %v, want %q", tc.name, got, "Front")
+		}
+	}
+}
+
+func TestCheckFS_frontMatterLinks(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"post.md": &fstest.MapFile{Data: []byte(`---
+title: Post
+image: /img/cover.png
+related:
+  - other.md#intro
+  - gone.md
+params:
+  thumb: thumb.png
+---
+# Post
+`)},
+		"other.md":      &fstest.MapFile{Data: []byte("# Intro\n")},
+		"img/cover.png": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:          func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		FrontMatterLinks: []string{"image", "related", "params.thumb", "title"},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d", l.Link.Raw, l.Link.LineStart))
+	}
+	if want := []string{"gone.md:6", "thumb.png:8", "Post:2"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_redirects(t *testing.T) {
+	t.Parallel()
+	redirects, err := ParseRedirects(strings.NewReader(`
+# comment
+/old.md   /new.md  301
+/blog/*   /posts/:splat
+/gone.md  /also-gone.md
+/ext.md   https://example.com/ext/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"doc.md":         &fstest.MapFile{Data: []byte("[a](old.md), [b](blog/first.md), [c](gone.md), [d](ext.md)\n")},
+		"new.md":         &fstest.MapFile{},
+		"posts/first.md": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:         func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Redirects:       redirects,
+		ReportRedirects: true,
+	}
+	err = c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%v", l.Link.Raw, l.IsWarning()))
+	}
+	if want := []string{"old.md:true", "blog/first.md:true", "gone.md:false", "ext.md:true"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_directoryIndex(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":            &fstest.MapFile{Data: []byte("[a](pkg/), [b](./pkg#usage), [c](docs/#install), [d](pkg/#nope), [e](nodir/)\n")},
+		"pkg/README.md":     &fstest.MapFile{Data: []byte("# Pkg\n\n## Usage\n")},
+		"docs/index.md":     &fstest.MapFile{Data: []byte("# Install\n")},
+		"docs/other/doc.md": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		DirectoryIndex: []string{"README.md", "index.md"},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := []string{"pkg/#nope", "nodir/"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_inferExtensions(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":       &fstest.MapFile{Data: []byte("[a](page), [b](sub/guide#setup), [c](sub/guide#nope), [d](missing)\n")},
+		"page.md":      &fstest.MapFile{},
+		"sub/guide.md": &fstest.MapFile{Data: []byte("## Setup\n")},
+	}
+	c := &Checker{
+		Matcher:         func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		InferExtensions: []string{".markdown", ".md"},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := []string{"sub/guide#nope", "missing"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_extensionMap(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":      &fstest.MapFile{Data: []byte("[a](guide.html#setup), [b](guide.html#nope), [c](static.html), [d](missing.html)\n")},
+		"guide.md":    &fstest.MapFile{Data: []byte("## Setup\n")},
+		"static.html": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:      func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		ExtensionMap: map[string]string{".html": ".md"},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := []string{"guide.html#nope", "missing.html"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_percentEncoding(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte(`# Doc
+
+* [space](my%20file.md#s%C3%A9ance)
+* [non-ascii](caf%C3%A9.md), [literal](café.md)
+* [bad escape](100%.md), [encoded percent](100%25.md), [literal percent](my%2520file.md)
+* [missing](no%20such%20file.md)
+`)},
+		"my file.md":   &fstest.MapFile{Data: []byte("# Séance\n")},
+		"café.md":      &fstest.MapFile{},
+		"100%.md":      &fstest.MapFile{},
+		"my%20file.md": &fstest.MapFile{},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := []string{"no%20such%20file.md"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_spaces(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte(`# Doc
+
+[a](<./my file.md>), [b](<sub dir/my file.md#second-header>), [c](my%20file.md),
+[d](<no such file.md>), [e](<sub dir/my file.md#nope>), <a href="my file.md">f</a>.
+`)},
+		"my file.md":         &fstest.MapFile{},
+		"sub dir/my file.md": &fstest.MapFile{Data: []byte("## Second header\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := []string{"no such file.md", "sub dir/my file.md#nope"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got broken links %q, want %q", got, want)
+	}
+
+	c.LintSpaces = true
+	if err := c.CheckFS(fsys); !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var warnings int
+	for _, l := range e.Links {
+		if l.IsWarning() {
+			warnings++
+		}
+	}
+	if warnings != 5 {
+		t.Fatalf("got %d warnings, want 5: %v", warnings, e.Links)
+	}
+}
+
+func TestCheckFS_normalizeUnicode(t *testing.T) {
+	t.Parallel()
+	const nfc, nfd = "caf\u00e9", "cafe\u0301"
+	fsys := fstest.MapFS{
+		"doc.md":         &fstest.MapFile{Data: []byte("[a](" + nfc + ".md), [b](" + nfc + "/page.md#header), [c](" + nfd + "/page.md)\n")},
+		nfd + ".md":      &fstest.MapFile{},
+		nfd + "/page.md": &fstest.MapFile{Data: []byte("# Header\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 2 {
+		t.Fatalf("got %d broken links without normalization, want 2: %v", len(e.Links), e.Links)
+	}
+	c.NormalizeUnicode = true
+	if err := c.CheckFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckFS_checkCase(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":           &fstest.MapFile{Data: []byte("[a](Readme.md), [b](README.md), [c](Sub/Page.md#x), [d](missing.md)\n")},
+		"README.md":        &fstest.MapFile{},
+		"sub/page.md":      &fstest.MapFile{},
+		"sub/Page.md.orig": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:   func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		CheckCase: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Kind()+" "+l.Suggestion)
+	}
+	want := []string{"case-mismatch README.md", "case-mismatch sub/page.md#x", "missing-file "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_suggestions(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/doc.md": &fstest.MapFile{Data: []byte(`[a](setup.md#install), [b](/isntall.md), [c](../guide/intro.md?x=1),
+[d](no-such-thing.md), [e](chapter.md), [f](/sub/two.md)
+`)},
+		"site/sub/two.md":    &fstest.MapFile{},
+		"x/two.md":           &fstest.MapFile{},
+		"guides/setup.md":    &fstest.MapFile{},
+		"install.md":         &fstest.MapFile{},
+		"guide/intro.mdx":    &fstest.MapFile{},
+		"a/chapter.md":       &fstest.MapFile{},
+		"b/chapter.md":       &fstest.MapFile{},
+		"docs/unrelated.txt": &fstest.MapFile{},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Suggestion)
+	}
+	want := []string{"../guides/setup.md#install", "/install.md", "../guide/intro.mdx?x=1", "", "", "/site/sub/two.md"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got suggestions %q, want %q", got, want)
+	}
+	if s, want := e.Links[0].String(), `docs/doc.md: link "setup.md#install" points to a non-existing file; did you mean "../guides/setup.md#install"?`; s != want {
+		t.Fatalf("got message %q, want %q", s, want)
+	}
+}
+
+func TestCheckFS_anchorSuggestions(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte(`# Getting started
+
+[a](#getting-startd), [b](other.md#instalation), [c](other.md#usage), [d](#zzz)
+
+## Extra
+`)},
+		"other.md": &fstest.MapFile{Data: []byte("# Installation\n\n## Usage 1\n\n## Usage 2\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Suggestion)
+	}
+	want := []string{"#getting-started", "other.md#installation", "", ""}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got suggestions %q, want %q", got, want)
+	}
+}
+
+func TestFixer(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/doc.md": &fstest.MapFile{Data: []byte(`# Getting started
+
+See [setup](setup.md#install), [it again](setup.md#install "title"),
+[readme](../Readme.md) and [start](#getting-startd), [reference][ref].
+
+[ref]: <setup.md#install>
+[keep]: no-such-thing.md
+`)},
+		"guides/setup.md": &fstest.MapFile{Data: []byte("# Install\n")},
+		"README.md":       &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:   func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		CheckCase: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	written := make(map[string][]byte)
+	f := &Fixer{WriteFile: func(name string, data []byte) error {
+		written[name] = data
+		return nil
+	}}
+	fixes, err := f.Fix(fsys, e.Links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 5 {
+		t.Errorf("got %d fixes, want 5: %+v", len(fixes), fixes)
+	}
+	want := `# Getting started
+
+See [setup](../guides/setup.md#install), [it again](../guides/setup.md#install "title"),
+[readme](../README.md) and [start](#getting-started), [reference][ref].
+
+[ref]: <../guides/setup.md#install>
+[keep]: no-such-thing.md
+`
+	if got := string(written["docs/doc.md"]); got != want {
+		t.Fatalf("got fixed document:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFixer_Move(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"README.md":       &fstest.MapFile{Data: []byte("[a](./guide/old.md), [b](/guide/old.md#header), [c](guide/)\n")},
+		"docs/doc.md":     &fstest.MapFile{Data: []byte("[a](../guide/old.md#header), [b](../guide/old), [c](../guide/intro.md)\n")},
+		"guide/old.md":    &fstest.MapFile{Data: []byte("# Header\n\n[a](intro.md), [b](#header), [c](/docs/doc.md), [d](../README.md)\n")},
+		"guide/intro.md":  &fstest.MapFile{},
+		"guide/image.png": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:         func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		InferExtensions: []string{".md"},
+	}
+	written := make(map[string]string)
+	f := &Fixer{WriteFile: func(name string, data []byte) error {
+		written[name] = string(data)
+		return nil
+	}}
+	if _, err := f.Move(c, fsys, "guide/old.md", "new/place/page.md"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"README.md":    "[a](./new/place/page.md), [b](/new/place/page.md#header), [c](guide/)\n",
+		"docs/doc.md":  "[a](../new/place/page.md#header), [b](../new/place/page), [c](../guide/intro.md)\n",
+		"guide/old.md": "# Header\n\n[a](../../guide/intro.md), [b](#header), [c](/docs/doc.md), [d](../../README.md)\n",
+	}
+	if len(written) != len(want) {
+		t.Errorf("got %d files written, want %d", len(written), len(want))
+	}
+	for name, w := range want {
+		if got := written[name]; got != w {
+			t.Errorf("%s: got\n%q\nwant\n%q", name, got, w)
+		}
+	}
+
+	// moving a directory
+	written = make(map[string]string)
+	if _, err := f.Move(c, fsys, "guide", "docs/guide"); err != nil {
+		t.Fatal(err)
+	}
+	if got, w := written["README.md"], "[a](./docs/guide/old.md), [b](/docs/guide/old.md#header), [c](docs/guide/)\n"; got != w {
+		t.Errorf("README.md: got\n%q\nwant\n%q", got, w)
+	}
+	if got, w := written["guide/old.md"], "# Header\n\n[a](intro.md), [b](#header), [c](/docs/doc.md), [d](../../README.md)\n"; got != w {
+		t.Errorf("guide/old.md: got\n%q\nwant\n%q", got, w)
+	}
+}
+
+func TestFixer_RenameAnchor(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"README.md":       &fstest.MapFile{Data: []byte("[a](guide/page.md#old-name), [b](guide/#old-name), [c](guide/page.md#other), [d](#old-name)\n")},
+		"guide/index.md":  &fstest.MapFile{Data: []byte("[a](page.md#old-name)\n")},
+		"guide/page.md":   &fstest.MapFile{Data: []byte("# New name\n\nSee [above](#old-name).\n")},
+		"guide/other.md":  &fstest.MapFile{Data: []byte("# Old name\n\n[a](#old-name), [b](/guide/page.md#old-name \"title\")\n")},
+		"guide/README.md": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		DirectoryIndex: []string{"page.md"},
+	}
+	written := make(map[string]string)
+	f := &Fixer{WriteFile: func(name string, data []byte) error {
+		written[name] = string(data)
+		return nil
+	}}
+	if _, err := f.RenameAnchor(c, fsys, "guide/page.md", "old-name", "new-name"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"README.md":      "[a](guide/page.md#new-name), [b](guide/#new-name), [c](guide/page.md#other), [d](#old-name)\n",
+		"guide/index.md": "[a](page.md#new-name)\n",
+		"guide/page.md":  "# New name\n\nSee [above](#new-name).\n",
+		"guide/other.md": "# Old name\n\n[a](#old-name), [b](/guide/page.md#new-name \"title\")\n",
+	}
+	if len(written) != len(want) {
+		t.Errorf("got %d files written, want %d", len(written), len(want))
+	}
+	for name, w := range want {
+		if got := written[name]; got != w {
+			t.Errorf("%s: got\n%q\nwant\n%q", name, got, w)
+		}
+	}
+}
+
+func TestChecker_Graph(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"README.md":      &fstest.MapFile{Data: []byte("[a](guide/), [b](#x), [c](missing.md), [d](https://example.org/)\n\n![img](img.png)\n")},
+		"guide/index.md": &fstest.MapFile{Data: []byte("[a](../README.md#top)\n")},
+		"img.png":        &fstest.MapFile{},
+		"lonely.md":      &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:        func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		DirectoryIndex: []string{"index.md"},
+	}
+	g, err := c.Graph(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"README.md", "guide/index.md", "lonely.md"}; strings.Join(g.Files, "|") != strings.Join(want, "|") {
+		t.Errorf("got files %q, want %q", g.Files, want)
+	}
+	var got []string
+	for _, e := range g.Edges {
+		got = append(got, fmt.Sprintf("%s:%d->%s", e.From, e.Link.LineStart, e.To))
+	}
+	want := []string{"README.md:1->guide/index.md", "README.md:3->img.png", "guide/index.md:1->README.md"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got edges %q, want %q", got, want)
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"README.md":     &fstest.MapFile{Data: []byte("[a](docs/page.md)\n\n[b](docs/page.md#header)\n")},
+		"docs/page.md":  &fstest.MapFile{Data: []byte("# Header\n\n[a](other.md), [b](#header)\n")},
+		"docs/other.md": &fstest.MapFile{Data: []byte("[a](./page.md)\n")},
+	}
+	index, err := Backlinks(fsys, "*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range index["docs/page.md"] {
+		got = append(got, fmt.Sprintf("%s:%d:%s", e.From, e.Link.LineStart, e.Link.Raw))
+	}
+	want := []string{"README.md:1:docs/page.md", "README.md:3:docs/page.md#header", "docs/other.md:1:./page.md"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got backlinks %q, want %q", got, want)
+	}
+	if l := index["README.md"]; len(l) != 0 {
+		t.Fatalf("got unexpected backlinks to README.md: %v", l)
+	}
+}
+
+func TestCheckFS_unusedReferences(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte(`# Doc
+
+See [full][used], [collapsed][], [shortcut], and [footnote][^1].
+
+[used]: https://example.org/
+[Collapsed]: doc.md
+[shortcut]: #doc
+[unused]: https://example.org/unused
+
+> - [nested unused]: doc.md "title"
+
+[^1]: Footnote.
+`)},
+	}
+	c := &Checker{
+		Matcher:          func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		UnusedReferences: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		if !l.IsWarning() || l.Kind() != "unused-reference" {
+			t.Errorf("unexpected report: %v", l)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", l.Link.LineStart, l.Link.Raw))
+	}
+	if want := []string{"8:unused", "10:nested unused"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_danglingReferences(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte("# Doc\n\n" +
+			"See [full][used], [broken][nope], ![image][Missing Image], and [collapsed][].\n\n" +
+			"Plain [brackets] and `code [x][y]` are fine, so is a footnote[^1] and [x][^2].\n\n" +
+			"[used]: #doc\n\n[^1]: Footnote.\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		if l.Kind() != "missing-reference" {
+			t.Errorf("unexpected report: %v", l)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", l.Link.LineStart, l.Link.Raw))
+	}
+	if want := []string{"3:nope", "3:Missing Image", "3:collapsed"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_referencesInCode(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte("# Doc\n\n" +
+			"```markdown\nSee [x][y] and [z][].\n\n[unused]: https://example.org/\n```\n\n" +
+			"    [x][y]\n    [unused]: https://example.org/\n\n" +
+			"Shortcuts [y] and [z] aren't links, [gone][y] is.\n\n" +
+			"[unused]: https://example.org/\n")},
+	}
+	c := &Checker{
+		Matcher:          func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		UnusedReferences: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%d:%s:%s", l.Link.LineStart, l.Kind(), l.Link.Raw))
+	}
+	if want := []string{"12:missing-reference:y", "14:unused-reference:unused"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_placeholderLinks(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md": &fstest.MapFile{Data: []byte(`# Doc
+
+[a]()
+
+[b](#)
+
+[c](TODO)
+
+[d](https://www.example.com/page)
+
+![e](<>)
+
+[f](#doc), [g](https://example.net.evil.test/), [h](#todo)
+
+## TODO
+`)},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Kind() != "missing-file" {
+		t.Fatalf("want only TODO link reported as missing file without PlaceholderLinks, got %v", e.Links)
+	}
+	c.PlaceholderLinks = true
+	if err := c.CheckFS(fsys); !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Kind()+" "+l.Link.Raw)
+	}
+	want := []string{"empty-link ", "placeholder-link #", "placeholder-link TODO", "placeholder-link https://www.example.com/page", "empty-link "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_lintPaths(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/doc.md": &fstest.MapFile{Data: []byte(`# Doc
+
+[a](doc.md)
+
+[b](doc.md#doc), [c](./x/../other.md#doc), [d](../docs/other.md), [e](/docs//other.md)
+
+[f](./other.md), [g](../), [h](sub/), [i](sub/../sub/)
+`)},
+		"docs/other.md":   &fstest.MapFile{Data: []byte("# Doc\n")},
+		"docs/sub/one.md": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher:   func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		LintPaths: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		if !l.IsWarning() {
+			t.Errorf("not a warning: %v", l)
+		}
+		got = append(got, l.Kind()+" "+l.Link.Raw+" "+l.Suggestion)
+	}
+	want := []string{
+		"self-link doc.md ",
+		"redundant-path ./x/../other.md#doc ./other.md#doc",
+		"redundant-path ../docs/other.md other.md",
+		"redundant-path /docs//other.md /docs/other.md",
+		"redundant-path sub/../sub/ sub/",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func Test_extractDocDetails_positions(t *testing.T) {
+	t.Parallel()
+	body := []byte(`---
+image: cover.png
+---
+# Doc
+
+Привет [a](x.md) and [b](x.md), ![c](img.png)
+[**d**](x.md#frag) and <a href="y.md">e</a> [ref][r]
+
+| [f](z.md) |
+|---|
+
+[r]: ref.md
+`)
+	d, err := extractDocDetails(body, &docOptions{parser: mdparser, frontMatterKeys: []string{"image"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range d.links {
+		if !strings.HasPrefix(string(body[l.Offset:]), l.Raw) {
+			t.Errorf("link %q: offset %d points to %q", l.Raw, l.Offset, body[l.Offset:])
+		}
+		got = append(got, fmt.Sprintf("%s:%d:%d", l.Raw, l.Line, l.Column))
+	}
+	want := []string{
+		"cover.png:2:8",
+		"x.md:6:18",
+		"x.md:6:32",
+		"img.png:6:44",
+		"x.md#frag:7:9",
+		"y.md:7:33",
+		"ref.md:12:6",
+		"z.md:9:7",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got positions\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckFS_concurrency(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{}
+	for i := 0; i < 50; i++ {
+		fsys[fmt.Sprintf("docs/%02d.md", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"# Doc %d\n\n[prev](%02d.md#doc-%d), [next](%02d.md#doc-%d), [bad](%02d.md#nope)\n\n[missing](Missing%d.md)\n",
+			i, (i+49)%50, (i+49)%50, (i+1)%50, (i+1)%50, (i+2)%50, i))}
+	}
+	check := func(concurrency int) ([]string, []string) {
+		var files []string
+		c := &Checker{
+			Matcher:     func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			CheckCase:   true,
+			Concurrency: concurrency,
+			OnFile:      func(p string) { files = append(files, p) },
+		}
+		err := c.CheckFS(fsys)
+		var e *BrokenLinksError
+		if !errors.As(err, &e) {
+			t.Fatalf("want *BrokenLinksError, got %v", err)
+		}
+		var links []string
+		for _, l := range e.Links {
+			links = append(links, l.String())
+		}
+		return files, links
+	}
+	wantFiles, wantLinks := check(1)
+	if len(wantFiles) != 50 || len(wantLinks) != 100 {
+		t.Fatalf("got %d files and %d broken links, want 50 and 100", len(wantFiles), len(wantLinks))
+	}
+	gotFiles, gotLinks := check(8)
+	if strings.Join(gotFiles, "\n") != strings.Join(wantFiles, "\n") {
+		t.Errorf("concurrent check visited files in a different order: %q", gotFiles)
+	}
+	if strings.Join(gotLinks, "\n") != strings.Join(wantLinks, "\n") {
+		t.Errorf("concurrent check reported:\n%s\n\nwant:\n%s", strings.Join(gotLinks, "\n"), strings.Join(wantLinks, "\n"))
+	}
+}
+
+func TestCheckFS_maxViolations(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("[one](1.md) [two](2.md)\n")},
+		"b.md": &fstest.MapFile{Data: []byte("[three](3.md)\n")},
+		"c.md": &fstest.MapFile{Data: []byte("[four](4.md)\n")},
+	}
+	for _, concurrency := range []int{0, 4} {
+		for limit, want := range map[int]string{
+			1: "1.md",
+			2: "1.md 2.md",
+			3: "1.md 2.md 3.md",
+			9: "1.md 2.md 3.md 4.md",
+		} {
+			var files []string
+			c := &Checker{
+				Matcher:       func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+				MaxViolations: limit,
+				Concurrency:   concurrency,
+				OnFile:        func(p string) { files = append(files, p) },
+			}
+			var e *BrokenLinksError
+			if err := c.CheckFS(fsys); !errors.As(err, &e) {
+				t.Fatalf("got error %v, want *BrokenLinksError", err)
+			}
+			var got []string
+			for _, l := range e.Links {
+				got = append(got, l.Link.Raw)
+			}
+			if strings.Join(got, " ") != want {
+				t.Errorf("concurrency %d, limit %d: got %q, want %q", concurrency, limit, got, want)
+			}
+			if limit == 1 && len(files) != 1 {
+				t.Errorf("concurrency %d, limit %d: checked files %q, want only the first one", concurrency, limit, files)
+			}
+		}
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }, MaxViolations: 2}
+	links, err := c.CheckFiles(fsys, "c.md", "b.md", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[1].Link.Raw != "3.md" {
+		t.Fatalf("CheckFiles: got %v, want links to 4.md and 3.md", links)
+	}
+	if !c.LimitReached(links) {
+		t.Error("LimitReached returned false for the stopped check")
+	}
+	if c.LimitReached(links[:1]) {
+		t.Error("LimitReached returned true for links below the limit")
+	}
+}
+
+func TestChecker_OnBroken(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("[x](missing.md)\n\n[y](#nope)\n")},
+		"b.md": &fstest.MapFile{Data: []byte("[z](a.md)\n")},
+		"c.md": &fstest.MapFile{Data: []byte("[w](gone.md)\n")},
+	}
+	var events []string
+	c := &Checker{
+		Matcher:  func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		OnBroken: func(l BrokenLink) { events = append(events, "broken "+l.Link.Raw) },
+		OnFile:   func(p string) { events = append(events, "file "+p) },
+	}
+	if err := c.CheckFS(fsys); err == nil {
+		t.Fatal("CheckFS returned nil error")
+	}
+	want := []string{"broken missing.md", "broken #nope", "file a.md", "file b.md", "broken gone.md", "file c.md"}
+	if strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", events, want)
+	}
+}
+
+func TestChecker_CheckFile(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md": &fstest.MapFile{Data: []byte("# A\n\n[b](b.md#b), [c](b.md#c), [d](/img/d.png), [e](../missing.md)\n")},
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n\n[broken](nowhere.md)\n")},
+		"img/d.png": &fstest.MapFile{},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	links, err := c.CheckFile(fsys, "docs/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range links {
+		got = append(got, l.String())
+	}
+	want := []string{
+		`docs/a.md: link "b.md#c" points to a non-existing slug; did you mean "b.md#b"?`,
+		`docs/a.md: link "../missing.md" points to a non-existing file`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if _, err := c.CheckFile(fsys, "docs/none.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("checking non-existing file: got %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestChecker_CheckFiles(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md": &fstest.MapFile{Data: []byte("# A\n\n[b](b.md#c)\n")},
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n\n[broken](nowhere.md)\n")},
+		"docs/c.md": &fstest.MapFile{Data: []byte("[broken](nowhere.md)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	links, err := c.CheckFiles(fsys, "docs/b.md", "docs/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range links {
+		got = append(got, l.File+" "+l.Link.Raw)
+	}
+	if want := "docs/b.md nowhere.md|docs/a.md b.md#c"; strings.Join(got, "|") != want {
+		t.Fatalf("got %q, want %q", strings.Join(got, "|"), want)
+	}
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md": &fstest.MapFile{Data: []byte("# A\n\n[b](b.md#setup)\n")},
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n\n## Setup\n")},
+		"docs/c.md": &fstest.MapFile{Data: []byte("[broken](nowhere.md)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	s, err := c.NewSession(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := func(files []string, links []BrokenLink) string {
+		var got []string
+		for _, l := range links {
+			got = append(got, l.File+" "+l.Link.Raw)
+		}
+		return strings.Join(files, ",") + ": " + strings.Join(got, "|")
+	}
+	files, links, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(files, links), "docs/a.md,docs/b.md,docs/c.md: docs/c.md nowhere.md"; got != want {
+		t.Fatalf("initial check: got %q, want %q", got, want)
+	}
+	fsys["docs/b.md"] = &fstest.MapFile{Data: []byte("# B\n\n## Install\n")}
+	if files, links, err = s.Update("docs/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(files, links), "docs/a.md,docs/b.md: docs/a.md b.md#setup"; got != want {
+		t.Fatalf("after changing the target: got %q, want %q", got, want)
+	}
+	fsys["docs/nowhere.md"] = &fstest.MapFile{}
+	if files, links, err = s.Update("docs/nowhere.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(files, links), "docs/c.md,docs/nowhere.md: "; got != want {
+		t.Fatalf("after creating the target: got %q, want %q", got, want)
+	}
+}
+
+func TestSession_dependents(t *testing.T) {
+	t.Parallel()
+	md := func(s string) (bool, error) { return path.Ext(s) == ".md", nil }
+	testCases := []struct {
+		name    string
+		c       *Checker
+		fsys    fstest.MapFS
+		changed string
+		data    string // new content of the changed file; if empty, it's removed
+		want    string
+	}{
+		{
+			name:    "wiki link",
+			c:       &Checker{Matcher: md, WikiLinks: WikiLinksShortestPath},
+			fsys:    fstest.MapFS{"notes/a.md": {Data: []byte("[[Page#Setup]]\n")}, "other/Page.md": {Data: []byte("# Page\n\n## Setup\n")}},
+			changed: "other/Page.md",
+			data:    "# Page\n",
+			want:    "notes/a.md,other/Page.md: notes/a.md [[Page#Setup]]",
+		},
+		{
+			name:    "removed wiki link target",
+			c:       &Checker{Matcher: md, WikiLinks: WikiLinksShortestPath},
+			fsys:    fstest.MapFS{"notes/a.md": {Data: []byte("[[Page]]\n")}, "other/Page.md": {Data: []byte("# Page\n")}},
+			changed: "other/Page.md",
+			want:    "notes/a.md: notes/a.md [[Page]]",
+		},
+		{
+			name:    "Hugo shortcode",
+			c:       &Checker{Matcher: md, HugoShortcodes: true},
+			fsys:    fstest.MapFS{"posts/a.md": {Data: []byte("{{< ref \"b.md#setup\" >}}\n")}, "posts/b.md": {Data: []byte("# B\n\n## Setup\n")}},
+			changed: "posts/b.md",
+			data:    "# B\n",
+			want:    "posts/a.md,posts/b.md: posts/a.md {{< ref \"b.md#setup\" >}}",
+		},
+		{
+			name:    "Jekyll post_url tag",
+			c:       &Checker{Matcher: md, JekyllTags: true},
+			fsys:    fstest.MapFS{"index.md": {Data: []byte("{% post_url 2023-01-01-hello %}\n")}, "_posts/2023-01-01-hello.md": {Data: []byte("# Hello\n")}},
+			changed: "_posts/2023-01-01-hello.md",
+			want:    "index.md: index.md {% post_url 2023-01-01-hello %}",
+		},
+		{
+			name:    "reStructuredText label",
+			c:       &Checker{Matcher: func(s string) (bool, error) { return isRST(s), nil }},
+			fsys:    fstest.MapFS{"guide.rst": {Data: []byte("See :ref:`setup`.\n")}, "other.rst": {Data: []byte(".. _Setup:\n\nSetup\n=====\n")}},
+			changed: "other.rst",
+			data:    "Setup\n=====\n",
+			want:    "guide.rst,other.rst: guide.rst setup",
+		},
+		{
+			name:    "directory index document",
+			c:       &Checker{Matcher: md, DirectoryIndex: []string{"index.md"}},
+			fsys:    fstest.MapFS{"a.md": {Data: []byte("[d](dir/#setup)\n")}, "dir/index.md": {Data: []byte("# Dir\n\n## Setup\n")}},
+			changed: "dir/index.md",
+			data:    "# Dir\n",
+			want:    "a.md,dir/index.md: a.md dir/#setup",
+		},
+		{
+			name:    "removed target with inferred extension",
+			c:       &Checker{Matcher: md, InferExtensions: []string{".md"}},
+			fsys:    fstest.MapFS{"a.md": {Data: []byte("[p](page)\n")}, "page.md": {Data: []byte("# Page\n")}},
+			changed: "page.md",
+			want:    "a.md: a.md page",
+		},
+		{
+			name:    "removed directory index document",
+			c:       &Checker{Matcher: md, DirectoryIndex: []string{"index.md"}},
+			fsys:    fstest.MapFS{"a.md": {Data: []byte("[d](dir/#setup)\n")}, "dir/index.md": {Data: []byte("# Dir\n\n## Setup\n")}, "dir/other.md": {}},
+			changed: "dir/index.md",
+			want:    "a.md: ", // fragments of directory links are not checked
+		},
+	}
+	for _, tc := range testCases {
+		s, err := tc.c.NewSession(tc.fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, links, err := s.Check(); err != nil || len(links) != 0 {
+			t.Fatalf("%s: initial check: got %v, %v", tc.name, links, err)
+		}
+		if tc.data == "" {
+			delete(tc.fsys, tc.changed)
+		} else {
+			tc.fsys[tc.changed] = &fstest.MapFile{Data: []byte(tc.data)}
+		}
+		files, links, err := s.Update(tc.changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, l := range links {
+			got = append(got, l.File+" "+l.Link.Raw)
+		}
+		if got := strings.Join(files, ",") + ": " + strings.Join(got, "|"); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestChecker_CheckBytes(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n\n[back](new.md#new)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	body := "# New\n\n[b](b.md#b), [self](new.md#new), [c](b.md#c), [top](#nope)\n"
+	links, err := c.CheckReader(fsys, "docs/new.md", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range links {
+		got = append(got, l.File+" "+l.Link.Raw)
+	}
+	if want := "docs/new.md b.md#c|docs/new.md #nope"; strings.Join(got, "|") != want {
+		t.Fatalf("got %q, want %q", strings.Join(got, "|"), want)
+	}
+	if _, err := c.CheckBytes(fsys, "../new.md", []byte(body)); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("checking document with invalid name: got %v, want fs.ErrInvalid", err)
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	t.Parallel()
+	body := []byte(`---
+title: Doc
+---
+# Intro
+
+See [local](other.md#setup), <https://example.org/>, [mail](mailto:me@example.org), and [none]().
+
+## Intro
+
+<a name="custom"></a><img src="img/x.png">
+`)
+	doc, err := ParseDocument(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []string
+	for _, l := range doc.Links {
+		links = append(links, fmt.Sprintf("%d:%d %q %q %q", l.Line, l.Column, l.Raw, l.Path, l.Fragment))
+	}
+	wantLinks := []string{
+		`6:13 "other.md#setup" "other.md" "setup"`,
+		`6:31 "https://example.org/" "" ""`,
+		`6:61 "mailto:me@example.org" "" ""`,
+		`6:89 "" "" ""`,
+		`10:32 "img/x.png" "img/x.png" ""`,
+	}
+	if strings.Join(links, "\n") != strings.Join(wantLinks, "\n") {
+		t.Errorf("links:\n%s\n\nwant:\n%s", strings.Join(links, "\n"), strings.Join(wantLinks, "\n"))
+	}
+	var anchors []string
+	for _, a := range doc.Anchors {
+		anchors = append(anchors, fmt.Sprintf("%d:%d %s", a.Line, a.Column, a.ID))
+	}
+	wantAnchors := []string{"4:1 intro", "8:1 intro-1", "10:10 custom"}
+	if strings.Join(anchors, "\n") != strings.Join(wantAnchors, "\n") {
+		t.Errorf("anchors:\n%s\n\nwant:\n%s", strings.Join(anchors, "\n"), strings.Join(wantAnchors, "\n"))
+	}
+	if doc.FrontMatter["title"] != "Doc" {
+		t.Errorf("front matter: got %v", doc.FrontMatter)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	t.Parallel()
+	for text, want := range map[string]string{
+		"Getting Started":      "getting-started",
+		"What's new in v1.2?":  "whats-new-in-v12",
+		"snake_case & kebab-x": "snake_case--kebab-x",
+		"Привет, мир":          "привет-мир",
+	} {
+		if got := Slugify(text, SlugDefault); got != want {
+			t.Errorf("Slugify(%q, SlugDefault) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_github(t *testing.T) {
+	t.Parallel()
+	// based on github-slugger fixtures
+	for text, want := range map[string]string{
+		"foo":                              "foo",
+		"foo bar":                          "foo-bar",
+		"foo  bar":                         "foo--bar",
+		"Foo-Bar_Baz":                      "foo-bar_baz",
+		"I ♥ unicode":                      "i--unicode",
+		"Привет non-ascii мир":             "привет-non-ascii-мир",
+		"😄 emoji":                          "-emoji",
+		"🙈 🙉 🙊":                            "--",
+		"Un été À Paris":                   "un-été-à-paris",
+		"中文标题":                             "中文标题",
+		"हिन्दी भाषा":                      "हिन्दी-भाषा",
+		"Hello, World!":                    "hello-world",
+		"heading with a - dash":            "heading-with-a---dash",
+		"heading with an _ underscore":     "heading-with-an-_-underscore",
+		"heading with a period.":           "heading-with-a-period",
+		"heading with an = sign":           "heading-with-an--sign",
+		"1. Numbered":                      "1-numbered",
+		"Tab\there":                        "tabhere",
+		"What's new in v1.2? (2024/01/02)": "whats-new-in-v12-20240102",
+		"a\u00a0b":                         "ab",
+	} {
+		if got := Slugify(text, SlugGitHub); got != want {
+			t.Errorf("Slugify(%q, SlugGitHub) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_gitlab(t *testing.T) {
+	t.Parallel()
+	// examples from GitLab documentation
+	for text, want := range map[string]string{
+		"This heading has spaces in it":                "this-heading-has-spaces-in-it",
+		"This heading has a :thumbsup: in it":          "this-heading-has-a-thumbsup-in-it",
+		"This heading has Unicode in it: 한글":           "this-heading-has-unicode-in-it-한글",
+		"This heading has 3.5 in it (and parentheses)": "this-heading-has-35-in-it-and-parentheses",
+		"This heading has  multiple - dashes":          "this-heading-has-multiple-dashes",
+		"2024":                                         "anchor-2024",
+		"2024 plans":                                   "2024-plans",
+	} {
+		if got := Slugify(text, SlugGitLab); got != want {
+			t.Errorf("Slugify(%q, SlugGitLab) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_kramdown(t *testing.T) {
+	t.Parallel()
+	// examples from kramdown documentation
+	for text, want := range map[string]string{
+		"This is a header":           "this-is-a-header",
+		"12. Another one 1 here":     "another-one-1-here",
+		"Do ^& it now":               "do--it-now",
+		"Hello, World! (2nd)":        "hello-world-2nd",
+		"Ünïcode Café":               "ncode-caf",
+		"123":                        "section",
+		"Don't forget _underscores_": "dont-forget-underscores",
+	} {
+		if got := Slugify(text, SlugKramdown); got != want {
+			t.Errorf("Slugify(%q, SlugKramdown) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_vscode(t *testing.T) {
+	t.Parallel()
+	for text, want := range map[string]string{
+		"  Getting   Started ":    "getting-started",
+		"What's new? (v1.2)":      "whats-new-v12",
+		"-- Dashes --":            "dashes",
+		"snake_case":              "snake_case",
+		"中文（标题）":                  "%E4%B8%AD%E6%96%87%E6%A0%87%E9%A2%98",
+		"Ünïcode":                 "%C3%BCn%C3%AFcode",
+		"C++ & C# <3 [brackets]!": "c--c-3-brackets",
+	} {
+		if got := Slugify(text, SlugVSCode); got != want {
+			t.Errorf("Slugify(%q, SlugVSCode) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_pandoc(t *testing.T) {
+	t.Parallel()
+	// examples from Pandoc documentation
+	for text, want := range map[string]string{
+		"Heading identifiers in HTML": "heading-identifiers-in-html",
+		"Maître d'hôtel":              "maître-dhôtel",
+		"Dogs?--in *my* house?":       "dogs--in-my-house",
+		"HTML, S5, or RTF?":           "html-s5-or-rtf",
+		"3. Applications":             "applications",
+		"33":                          "section",
+		"v1.2_final":                  "v1.2_final",
+	} {
+		if got := Slugify(text, SlugPandoc); got != want {
+			t.Errorf("Slugify(%q, SlugPandoc) = %q, want %q", text, got, want)
+		}
+	}
+	for text, want := range map[string]string{
+		"Heading identifiers in HTML": "heading-identifiers-in-html",
+		"3. Applications":             "3-applications",
+		"v1.2_final":                  "v12_final",
+		"Dogs?--in my house?":         "dogs--in-my-house",
+	} {
+		if got := Slugify(text, SlugPandocGFM); got != want {
+			t.Errorf("Slugify(%q, SlugPandocGFM) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSlugify_azureDevOps(t *testing.T) {
+	t.Parallel()
+	for text, want := range map[string]string{
+		"Getting Started":  "getting-started",
+		"Hello-World":      "hello%2Dworld",
+		"C# & .NET v1.2":   "c%23-%26-.net-v1.2",
+		"Ünïcode":          "%C3%BCn%C3%AFcode",
+		"snake_case (new)": "snake_case-%28new%29",
+	} {
+		if got := Slugify(text, SlugAzureDevOps); got != want {
+			t.Errorf("Slugify(%q, SlugAzureDevOps) = %q, want %q", text, got, want)
+		}
+	}
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("# Hello-World\n\n## C# Guide\n\n[1](#hello%2Dworld), [2](#c%23-guide), [3](#hello-world-1)\n")},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Slugger: func(text string) string { return Slugify(text, SlugAzureDevOps) },
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "#hello-world-1" {
+		t.Fatalf("got %v, want a single broken #hello-world-1 link", e.Links)
+	}
+}
+
+func TestChecker_Links(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md": &fstest.MapFile{Data: []byte("# A\n\n[ext](https://example.org/), [b](b), [top](#a)\n\n[gone](/missing.md)\n")},
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n")},
+	}
+	c := &Checker{
+		Matcher:         func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		InferExtensions: []string{".md"},
+	}
+	files, err := c.Links(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.File)
+		for _, l := range f.Links {
+			got = append(got, fmt.Sprintf("  %d:%d %s %s external=%t exists=%t", l.Line, l.Column, l.Raw, l.Target, l.External, l.Exists))
+		}
+	}
+	want := []string{
+		"docs/a.md",
+		"  3:7 https://example.org/  external=true exists=false",
+		"  3:34 b docs/b.md external=false exists=true",
+		"  3:44 #a docs/a.md external=false exists=true",
+		"  5:8 /missing.md missing.md external=false exists=false",
+		"docs/b.md",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	fl, err := c.DocumentLinks(fsys, "docs/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%+v", fl), fmt.Sprintf("%+v", files[0]); got != want {
+		t.Fatalf("DocumentLinks: got %s, want %s", got, want)
+	}
+}
+
+func TestChecker_Anchors(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":    &fstest.MapFile{Data: []byte("# Guide\n\n## Setup *fast*\n\nText <span id=\"note\"></span>\n\n### Setup fast {#custom}\n\n## Setup fast\n")},
+		"page.html": &fstest.MapFile{Data: []byte(`<h1 id="top">Top</h1><a name="x"></a>`)},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	anchors, err := c.Anchors(fsys, "doc.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range anchors {
+		got = append(got, fmt.Sprintf("%d %d %s %q", a.Line, a.Level, a.ID, a.Text))
+	}
+	want := []string{
+		`1 1 guide "Guide"`,
+		`3 2 setup-fast "Setup fast"`,
+		`5 0 note ""`,
+		`7 3 custom "Setup fast"`,
+		`9 2 setup-fast-1 "Setup fast"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if anchors, err = c.Anchors(fsys, "page.html"); err != nil {
+		t.Fatal(err)
+	}
+	if len(anchors) != 2 || anchors[0].ID != "top" || anchors[1].ID != "x" {
+		t.Errorf("html anchors: got %+v", anchors)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		from, dest     string
+		target, anchor string
+	}{
+		{"docs/a.md", "b.md#setup", "docs/b.md", "setup"},
+		{"docs/a.md", "../img/x.png", "img/x.png", ""},
+		{"docs/a.md", "/README.md", "README.md", ""},
+		{"docs/a.md", "#top", "docs/a.md", "top"},
+		{"docs/a.md", "my%20file.md", "docs/my file.md", ""},
+		{"a.md", "../../x.md", "../../x.md", ""},
+		{"a.md", "https://example.org/#x", "", ""},
+		{"a.md", "mailto:me@example.org", "", ""},
+	} {
+		target, anchor := ResolveLink(tc.from, tc.dest)
+		if target != tc.target || anchor != tc.anchor {
+			t.Errorf("ResolveLink(%q, %q) = %q, %q; want %q, %q", tc.from, tc.dest, target, anchor, tc.target, tc.anchor)
+		}
+	}
+}
+
+func TestCheckFS_slugger(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("# Getting Started\n\n## Getting Started\n\n## Custom {#id}\n\n" +
+			"[1](#Getting_Started), [2](#Getting_Started-1), [3](#id), [4](#getting-started)\n")},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Slugger: func(text string) string { return strings.ReplaceAll(text, " ", "_") },
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "#getting-started" {
+		t.Fatalf("got %v, want a single broken #getting-started link", e.Links)
+	}
+}
+
+func TestCheckFS_fragmentValid(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md": &fstest.MapFile{Data: []byte("# A\n\n[1](#tab-linux), [2](#nope), [3](b.md#tab-mac), [4](b.md#gone)\n")},
+		"docs/b.md": &fstest.MapFile{Data: []byte("# B\n")},
+	}
+	var calls []string
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		FragmentValid: func(target, fragment string) (bool, error) {
+			calls = append(calls, target+"#"+fragment)
+			return strings.HasPrefix(fragment, "tab-"), nil
+		},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if strings.Join(got, " ") != "#nope b.md#gone" {
+		t.Errorf("got broken links %q, want #nope and b.md#gone", got)
+	}
+	want := "docs/a.md#tab-linux docs/a.md#nope docs/b.md#tab-mac docs/b.md#gone"
+	if strings.Join(calls, " ") != want {
+		t.Errorf("FragmentValid calls: got %q, want %q", strings.Join(calls, " "), want)
+	}
+	c.FragmentValid = func(string, string) (bool, error) { return false, io.ErrUnexpectedEOF }
+	if err := c.CheckFS(fsys); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want error from FragmentValid", err)
+	}
+}
+
+func TestCheckFS_targetExists(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/a.md":  &fstest.MapFile{Data: []byte("# A\n\n[1](api/index.html#x), [2](b.md#b), [3](b.md#nope), [4](missing.md), [5](../ignored.md)\n")},
+		"docs/b.md":  &fstest.MapFile{Data: []byte("# B\n")},
+		"ignored.md": &fstest.MapFile{},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		TargetExists: func(fsys fs.FS, p string) bool {
+			if strings.HasPrefix(p, "docs/api/") {
+				return true // generated at build time
+			}
+			if p == "ignored.md" {
+				return false
+			}
+			_, err := fs.Stat(fsys, p)
+			return err == nil
+		},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Link.Raw)
+	}
+	if want := "b.md#nope missing.md ../ignored.md"; strings.Join(got, " ") != want {
+		t.Errorf("got broken links %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestCheckFS_extractors(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("# A\n\n{{include \"b.md\"}}\n[x](b.md#nope)\n\n{{include \"missing.md\"}} {{include \"b.md#b\"}}\n")},
+		"b.md": &fstest.MapFile{Data: []byte("# B\n")},
+	}
+	re := regexp.MustCompile(`{{include "([^"]+)"}}`)
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Extractors: []LinkExtractor{func(source []byte, _ ast.Node) ([]LinkInfo, error) {
+			var out []LinkInfo
+			for _, m := range re.FindAllSubmatchIndex(source, -1) {
+				out = append(out, LinkInfo{Raw: string(source[m[2]:m[3]]), Offset: m[2]})
+			}
+			return out, nil
+		}},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l.Link.Raw, l.Link.Line, l.Link.Column))
+	}
+	if want := "b.md#nope:4:5 missing.md:6:12"; strings.Join(got, " ") != want {
+		t.Errorf("got broken links %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestCheckFS_parserOptions(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("# A\n\nText %%[x](hidden.md)%% [y](missing.md)\n\nTerm\n: [z](gone.md)\n")},
+	}
+	c := &Checker{
+		Matcher:       func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Extensions:    []goldmark.Extender{extension.DefinitionList},
+		ParserOptions: []parser.Option{parser.WithInlineParsers(util.Prioritized(commentParser{}, 100))},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d", l.Link.Raw, l.Link.LineStart))
+	}
+	if want := "missing.md:3 gone.md:6"; strings.Join(got, " ") != want {
+		t.Errorf("got broken links %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+// commentParser is a goldmark inline parser skipping “%%comments%%”.
+type commentParser struct{}
+
+func (commentParser) Trigger() []byte { return []byte{'%'} }
+
+func (commentParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("%%")) {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("%%"))
+	if end < 0 {
+		return nil
+	}
+	block.Advance(end + 4)
+	return ast.NewText()
+}
+
+func TestCheckFS_dialect(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"a.md": &fstest.MapFile{Data: []byte("## Install {#inst}\n\n[1](#inst) [2](#install-inst) https://example.org/\n")},
+	}
+	for _, tc := range []struct {
+		dialect  Dialect
+		broken   string
+		external int
+	}{
+		{DialectDefault, "#install-inst", 1},
+		{DialectCommonMark, "#inst", 0},
+		{DialectGFM, "#inst", 1},
+		{DialectGoldmark, "#install-inst", 1},
+	} {
+		var got []string
+		c := &Checker{
+			Matcher:  func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			Dialect:  tc.dialect,
+			OnBroken: func(l BrokenLink) { got = append(got, l.Link.Raw) },
+		}
+		if err := c.CheckFS(fsys); err == nil {
+			t.Errorf("dialect %d: want error, got nil", tc.dialect)
+		}
+		if strings.Join(got, " ") != tc.broken {
+			t.Errorf("dialect %d: got broken links %q, want %q", tc.dialect, got, tc.broken)
+		}
+		files, err := c.Links(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var external int
+		for _, l := range files[0].Links {
+			if l.External {
+				external++
+			}
+		}
+		if external != tc.external {
+			t.Errorf("dialect %d: got %d external links, want %d", tc.dialect, external, tc.external)
+		}
+	}
+}
+
+func TestCheckFS_notebook(t *testing.T) {
+	t.Parallel()
+	const nb = `{"cells": [
+ {"cell_type": "markdown", "source": ["# Analysis\n", "\n", "See [data](data.md) and [setup](#setup)."]},
+ {"cell_type": "code", "source": ["print('[x](code.md)')"]},
+ {"cell_type": "markdown", "source": "[ref][nope]\n\n## Results\n\n[gone](missing.md) [back](#analysis)"}
+], "nbformat": 4}`
+	fsys := fstest.MapFS{
+		"nb.ipynb": &fstest.MapFile{Data: []byte(nb)},
+		"data.md":  &fstest.MapFile{Data: []byte("[results](nb.ipynb#results) [nope](nb.ipynb#nope)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md" || isNotebook(s), nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l, l.Link.Line, l.Link.Column))
+	}
+	want := []string{
+		`data.md: link "nb.ipynb#nope" points to a non-existing slug:1:36`,
+		`nb.ipynb (cell 1): link "#setup" points to a non-existing local slug:3:33`,
+		`nb.ipynb (cell 3): reference [nope] has no definition:1:7`,
+		`nb.ipynb (cell 3): link "missing.md" points to a non-existing file:5:8`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_asciiDoc(t *testing.T) {
+	t.Parallel()
+	const doc = `= Guide
+:idprefix:
+:idseparator: -
+
+== Getting Started
+
+See xref:other.adoc#_setup[setup], <<getting-started>>, <<getting-startd,typo>>,
+and xref:other#nope[].
+
+[[custom]]
+== Custom Title
+
+link:files/manual.pdf[Manual] image::missing.png[]
+
+----
+xref:ignored.adoc[]
+----
+`
+	fsys := fstest.MapFS{
+		"guide.adoc":       &fstest.MapFile{Data: []byte(doc)},
+		"other.adoc":       &fstest.MapFile{Data: []byte("= Other\n\n== Setup\n\n<<custom>> xref:guide.adoc#custom[]\n")},
+		"files/manual.pdf": &fstest.MapFile{},
+		"index.md":         &fstest.MapFile{Data: []byte("[a](guide.adoc#getting-started) [b](other.adoc#setup)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md" || isAsciiDoc(s), nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l, l.Link.Line, l.Link.Column))
+	}
+	want := []string{
+		`guide.adoc: link "getting-startd" points to a non-existing local slug; did you mean "getting-started"?:7:59`,
+		`guide.adoc: link "other#nope" points to a non-existing slug:8:10`,
+		`guide.adoc: link "missing.png" points to a non-existing file:13:38`,
+		`index.md: link "other.adoc#setup" points to a non-existing slug; did you mean "other.adoc#_setup"?:1:37`,
+		`other.adoc: link "custom" points to a non-existing local slug:5:3`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_rst(t *testing.T) {
+	t.Parallel()
+	const doc = "" +
+		"=====\n" +
+		"Guide\n" +
+		"=====\n" +
+		"\n" +
+		".. _install:\n" +
+		"\n" +
+		"Installing the Tool\n" +
+		"-------------------\n" +
+		"\n" +
+		"See :doc:`other`, :doc:`Missing <missing>`, :ref:`setup`, :ref:`Typo <instal>`,\n" +
+		"and `the manual <files/manual.pdf>`_.\n" +
+		"\n" +
+		".. image:: img/missing.png\n" +
+		"\n" +
+		"Example::\n" +
+		"\n" +
+		"    :doc:`ignored`\n"
+	fsys := fstest.MapFS{
+		"guide.rst":        &fstest.MapFile{Data: []byte(doc)},
+		"other.rst":        &fstest.MapFile{Data: []byte(".. _Setup:\n\nSetup\n=====\n\n:ref:`install`\n")},
+		"files/manual.pdf": &fstest.MapFile{},
+		"index.md":         &fstest.MapFile{Data: []byte("[a](guide.rst#installing-the-tool) [b](other.rst#setup) [c](guide.rst#nope)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md" || isRST(s), nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l, l.Link.Line, l.Link.Column))
+	}
+	want := []string{
+		`guide.rst: link "missing" points to a non-existing file:10:34`,
+		`guide.rst: label "instal" is not defined in any document; did you mean "install"?:10:71`,
+		`guide.rst: link "img/missing.png" points to a non-existing file:13:12`,
+		`index.md: link "guide.rst#nope" points to a non-existing slug:1:61`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_goDoc(t *testing.T) {
+	t.Parallel()
+	const src = "// Package x does things, see docs/usage.md and [guide].\n" +
+		"//\n" +
+		"// [guide]: ../guide.md\n" +
+		"package x\n" +
+		"\n" +
+		"// T is described in [the spec](spec.md), use [T.Run], not [T.Walk] or [Missing];\n" +
+		"// [pkg.Name], [string], and [word] are fine. See also README.md and x/y.md.\n" +
+		"//\n" +
+		"//\tcode/sample.md [NotALink]\n" +
+		"type T struct{}\n" +
+		"\n" +
+		"// Run runs, see https://example.com/.\n" +
+		"func (*T) Run() {}\n" +
+		"\n" +
+		"// Quoted examples, like “{% link path/to/file.md %}”, “[usage](gone/usage.md)”, “[Name]”,\n" +
+		"// “[text](guide.md#ins”, “{% post_url\n" +
+		"// path/to/post.md %}”, \"[Other]\", and `other/gone.md`, are not links.\n" +
+		"func Example() {}\n"
+	fsys := fstest.MapFS{
+		"pkg/x/x.go":    &fstest.MapFile{Data: []byte(src)},
+		"pkg/x/y.go":    &fstest.MapFile{Data: []byte("package x\n\n// Helper is a helper.\nfunc Helper() {}\n")},
+		"docs/usage.md": &fstest.MapFile{},
+		"pkg/guide.md":  &fstest.MapFile{},
+		"README.md":     &fstest.MapFile{},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return isGoFile(s), nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l, l.Link.Line, l.Link.Column))
+	}
+	want := []string{
+		`pkg/x/x.go: link "spec.md" points to a non-existing file:6:33`,
+		`pkg/x/x.go: doc link [T.Walk] refers to an undeclared identifier:6:61`,
+		`pkg/x/x.go: doc link [Missing] refers to an undeclared identifier:6:73`,
+		`pkg/x/x.go: link "x/y.md" points to a non-existing file:7:70`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_fileExtractors(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nav.yaml":   &fstest.MapFile{Data: []byte("nav:\n  - index.md\n  - missing.md\n")},
+		"page.html":  &fstest.MapFile{Data: []byte(`<h2 id="top">Top</h2><a href="index.md#intro">a</a> <a href="#top">b</a> <a href="#nope">c</a>`)},
+		"index.md":   &fstest.MapFile{Data: []byte("# Intro\n\n[nav](nav.yaml#x) [page](page.html#top) [page](page.html#bottom)\n")},
+		"ignored.go": &fstest.MapFile{Data: []byte("not go")},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) != ".go", nil },
+		FileExtractors: map[string]LinkExtractor{
+			".yaml": RegexpExtractor(regexp.MustCompile(`(?m)^\s*- (\S+\.md)$`)),
+			".html": RegexpExtractor(regexp.MustCompile(`href="([^"]+)"`)),
+		},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, fmt.Sprintf("%s:%d:%d", l, l.Link.Line, l.Link.Column))
+	}
+	want := []string{
+		`index.md: link "page.html#bottom" points to a non-existing slug:3:48`,
+		`nav.yaml: link "missing.md" points to a non-existing file:3:5`,
+		`page.html: link "#nope" points to a non-existing local slug:1:83`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckFS_lineFragments(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"src/main.go": &fstest.MapFile{Data: []byte("package main\n\nfunc main() {}")},
+		"doc.md":      &fstest.MapFile{Data: []byte("# Doc\n\nLine 3\n")},
+		"index.md": &fstest.MapFile{Data: []byte("[1](src/main.go#L3) [2](src/main.go#L2-L3) [3](src/main.go#L4)\n" +
+			"[4](src/main.go#L3-L2) [5](doc.md#L3) [6](doc.md#L10C1-L12C5) [7](src/#L1)\n")},
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		if l.Kind() != "line-out-of-range" {
+			t.Errorf("%v: got kind %q, want line-out-of-range", l, l.Kind())
+		}
+		got = append(got, l.Link.Raw)
+	}
+	if want := "src/main.go#L4 src/main.go#L3-L2 doc.md#L10C1-L12C5"; strings.Join(got, " ") != want {
+		t.Errorf("got broken links %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestCheckFS_repoURL(t *testing.T) {
+	t.Parallel()
+	const repo = "https://github.com/org/repo"
+	fsys := fstest.MapFS{
+		"docs/setup.md": &fstest.MapFile{Data: []byte("# Setup\n")},
+		"index.md": &fstest.MapFile{Data: []byte(`# Index
+
+[a](https://github.com/org/repo/blob/main/docs/setup.md#setup)
+[b](https://github.com/org/repo/tree/v1.0/docs)
+[c](https://github.com/org/repo/blob/main/docs/setup.md#setu)
+[d](https://github.com/org/repo/blob/main/setup.md)
+[e](https://github.com/org/other/blob/main/missing.md)
+[f](https://github.com/org/repo/issues/1)
+`)},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		RepoURL: repo + "/",
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Kind()+" "+l.Link.Raw+" "+l.Suggestion)
+	}
+	want := []string{
+		"missing-anchor " + repo + "/blob/main/docs/setup.md#setu " + repo + "/blob/main/docs/setup.md#setup",
+		"missing-file " + repo + "/blob/main/setup.md " + repo + "/blob/main/docs/setup.md",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_siteURL(t *testing.T) {
+	t.Parallel()
+	const site = "https://docs.example.com/v2"
+	fsys := fstest.MapFS{
+		"guide/setup.md": &fstest.MapFile{Data: []byte("# Setup\n")},
+		"index.md": &fstest.MapFile{Data: []byte(`# Index
+
+[a](https://docs.example.com/v2/guide/setup.html#setup)
+[b](https://docs.example.com/v2/guide/)
+[c](https://docs.example.com/v2/)
+[d](https://docs.example.com/v2/guide/setup.html#setu)
+[e](https://docs.example.com/v2/setup.html)
+[f](https://docs.example.com/v1/missing.html)
+`)},
+	}
+	c := &Checker{
+		Matcher:      func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		SiteURL:      site,
+		ExtensionMap: map[string]string{".html": ".md"},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.Kind()+" "+l.Link.Raw+" "+l.Suggestion)
+	}
+	want := []string{
+		"missing-anchor " + site + "/guide/setup.html#setu " + site + "/guide/setup.html#setup",
+		"missing-file " + site + "/setup.html " + site + "/guide/setup.md",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_queryStrings(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"doc.md":   &fstest.MapFile{Data: []byte("# Doc\n")},
+		"index.md": &fstest.MapFile{Data: []byte("[a](doc.md?highlight=x#doc) [b](doc.md) [c](missing.md?x=1)\n")},
+	}
+	for _, tc := range []struct {
+		policy QueryPolicy
+		want   string
+	}{
+		{QueryIgnore, "missing-file x=1"},
+		{QueryWarn, "query-string highlight=x|query-string x=1|missing-file x=1"},
+		{QueryError, "forbidden-query highlight=x|forbidden-query x=1"},
+	} {
+		c := &Checker{
+			Matcher:      func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			QueryStrings: tc.policy,
+		}
+		err := c.CheckFS(fsys)
+		var e *BrokenLinksError
+		if !errors.As(err, &e) {
+			t.Fatalf("want *BrokenLinksError, got %v", err)
+		}
+		var got []string
+		for _, l := range e.Links {
+			got = append(got, strings.TrimSpace(l.Kind()+" "+l.Link.Query))
+		}
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("policy %d: got %q, want %q", tc.policy, strings.Join(got, "|"), tc.want)
+		}
+	}
+}
+
+func TestCheckFS_mailto(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"index.md": &fstest.MapFile{Data: []byte(`# Index
+
+[a](mailto:team@example.com) [b](mailto:Team%20Lead%20%3Clead@example.com%3E?subject=Hi)
+[c](mailto:team@example..com) [d](mailto:?subject=Hi) [e](mailto:a@example.com,b@example.com?cc=A@example.com)
+[f](MAILTO:team@example.com,) [g](mailto:a@example.com,b@example.com)
+`)},
+	}
+	c := &Checker{
+		Matcher:     func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		CheckMailto: true,
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		if !l.IsWarning() {
+			t.Errorf("not a warning: %v", l)
+		}
+		got = append(got, l.Kind()+" "+l.Link.Raw)
+	}
+	want := []string{
+		"invalid-email mailto:team@example..com",
+		"empty-mailto mailto:?subject=Hi",
+		"duplicate-email mailto:a@example.com,b@example.com?cc=A@example.com",
+		"invalid-email MAILTO:team@example.com,",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFS_linkStyle(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/guide.md":     &fstest.MapFile{Data: []byte("# Guide\n")},
+		"docs/sub/index.md": &fstest.MapFile{Data: []byte("[a](../guide.md#guide) [b](/docs/guide.md) [c](#x) [d](./)\n\n# X\n")},
+		"README.md":         &fstest.MapFile{Data: []byte("[e](docs/) [f](/README.md)\n")},
+	}
+	for _, tc := range []struct {
+		style LinkStyle
+		want  []string
+	}{
+		{LinkStyleRelative, []string{
+			"absolute-link /README.md README.md",
+			"absolute-link /docs/guide.md ../guide.md",
+		}},
+		{LinkStyleAbsolute, []string{
+			"relative-link docs/ /docs/",
+			"relative-link ../guide.md#guide /docs/guide.md#guide",
+			"relative-link ./ /docs/sub/",
+		}},
+	} {
+		c := &Checker{
+			Matcher:   func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			LinkStyle: tc.style,
+		}
+		err := c.CheckFS(fsys)
+		var e *BrokenLinksError
+		if !errors.As(err, &e) {
+			t.Fatalf("want *BrokenLinksError, got %v", err)
+		}
+		var got []string
+		for _, l := range e.Links {
+			if !l.IsWarning() {
+				t.Errorf("not a warning: %v", l)
+			}
+			got = append(got, l.Kind()+" "+l.Link.Raw+" "+l.Suggestion)
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("style %d: got %q, want %q", tc.style, got, tc.want)
+		}
+	}
+}
+
+func TestCheckFS_outsideRoot(t *testing.T) {
+	t.Parallel()
+	outer := fstest.MapFS{
+		"CONTRIBUTING.md":   &fstest.MapFile{Data: []byte("# Contributing\n")},
+		"docs/guide.md":     &fstest.MapFile{Data: []byte("# Guide\n")},
+		"docs/sub/index.md": &fstest.MapFile{Data: []byte("[a](../../CONTRIBUTING.md#contributing) [b](../../LICENSE) [c](../../../x.md) [d](../guide.md)\n")},
+	}
+	fsys, err := fs.Sub(outer, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		outer fs.FS
+		want  string
+	}{
+		{nil, "outside-root ../../CONTRIBUTING.md#contributing|outside-root ../../LICENSE|outside-root ../../../x.md"},
+		{outer, "missing-file ../../LICENSE|outside-root ../../../x.md"},
+	} {
+		c := &Checker{
+			Matcher:  func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+			OuterFS:  tc.outer,
+			OuterDir: "docs",
+		}
+		err := c.CheckFS(fsys)
+		var e *BrokenLinksError
+		if !errors.As(err, &e) {
+			t.Fatalf("want *BrokenLinksError, got %v", err)
+		}
+		var got []string
+		for _, l := range e.Links {
+			got = append(got, l.Kind()+" "+l.Link.Raw)
+		}
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("got %q, want %q", strings.Join(got, "|"), tc.want)
+		}
+	}
+}
+
+func TestCheckFS_mounts(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"docs/index.md": &fstest.MapFile{Data: []byte("[a](/api/x.html#setup) [b](../projects/lib/README.md#lib) [c](/api/missing.html)\n" +
+			"[d](../projects/lib/README.md#nope) [e](/api/stale.md)\n")},
+		"api/stale.md": &fstest.MapFile{Data: []byte("[broken](nowhere.md)\n")},
+	}
+	c := &Checker{
+		Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil },
+		Mounts: map[string]fs.FS{
+			"api":          fstest.MapFS{"x.html": &fstest.MapFile{Data: []byte(`<h2 id="setup">Setup</h2>`)}},
+			"projects/lib": fstest.MapFS{"README.md": &fstest.MapFile{Data: []byte("# Lib\n")}},
+		},
+	}
+	err := c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.File+" "+l.Kind()+" "+l.Link.Raw)
+	}
+	want := []string{
+		"docs/index.md missing-file /api/missing.html",
+		"docs/index.md missing-anchor ../projects/lib/README.md#nope",
+		"docs/index.md missing-file /api/stale.md",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMonorepoFS(t *testing.T) {
+	t.Parallel()
+	fsys, err := MonorepoFS(map[string]fs.FS{
+		"services/a": fstest.MapFS{"README.md": &fstest.MapFile{Data: []byte("[b](../b/docs/setup.md#setup) [c](/libs/c/README.md) [d](../b/missing.md)\n")}},
+		"services/b": fstest.MapFS{"docs/setup.md": &fstest.MapFile{Data: []byte("# Setup\n\n[a](../../a/README.md#nope)\n")}},
+		"libs/c":     fstest.MapFS{"README.md": &fstest.MapFile{Data: []byte("# C\n")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err = c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	var got []string
+	for _, l := range e.Links {
+		got = append(got, l.File+" "+l.Link.Raw)
+	}
+	want := []string{
+		"services/a/README.md ../b/missing.md",
+		"services/b/docs/setup.md ../../a/README.md#nope",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if _, err := MonorepoFS(map[string]fs.FS{"a": fstest.MapFS{}, "a/b": fstest.MapFS{}}); err == nil {
+		t.Error("nested packages: want error")
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, body string }{
+		{"./docs/", ""},
+		{"./docs/index.md", "[a](guide/setup.md#setup) [b](missing.md)\n"},
+		{"./docs/guide/setup.md", "# Setup\n"},
+	} {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(f.name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, f.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := TarFS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "docs/index.md", "docs/guide/setup.md"); err != nil {
+		t.Fatal(err)
+	}
+	c := &Checker{Matcher: func(s string) (bool, error) { return path.Ext(s) == ".md", nil }}
+	err = c.CheckFS(fsys)
+	var e *BrokenLinksError
+	if !errors.As(err, &e) {
+		t.Fatalf("want *BrokenLinksError, got %v", err)
+	}
+	if len(e.Links) != 1 || e.Links[0].Link.Raw != "missing.md" {
+		t.Fatalf("got %v, want a single missing.md link", e.Links)
+	}
+}
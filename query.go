@@ -0,0 +1,39 @@
+package mdlinks
+
+import "strings"
+
+// QueryPolicy selects how local links with query strings, like
+// “page.md?highlight=x”, are treated.
+type QueryPolicy byte
+
+const (
+	// QueryIgnore checks such links with their query strings stripped.
+	QueryIgnore QueryPolicy = iota
+
+	// QueryWarn reports such links as warnings, and also checks them with
+	// their query strings stripped.
+	QueryWarn
+
+	// QueryError reports such links as broken, as query strings have no
+	// meaning for files.
+	QueryError
+)
+
+// setQueries sets the Query field of local links of d.
+func setQueries(d *docDetails) {
+	for i := range d.links {
+		d.links[i].Query = linkQuery(d.links[i].Raw)
+	}
+	for i := range d.all {
+		if d.all[i].Path != "" || d.all[i].Fragment != "" {
+			d.all[i].Query = linkQuery(d.all[i].Raw)
+		}
+	}
+}
+
+// linkQuery returns the query part of the raw link, without '?'.
+func linkQuery(raw string) string {
+	raw, _, _ = strings.Cut(raw, "#")
+	_, q, _ := strings.Cut(raw, "?")
+	return q
+}
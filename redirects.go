@@ -0,0 +1,69 @@
+package mdlinks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ParseRedirects parses redirect rules in the Netlify “_redirects” file
+// format: each line holds the source path, the destination path, and an
+// optional status code, separated by whitespace. Empty lines and lines
+// starting with “#” are ignored. The same format can be used for a simple
+// old to new path mapping. A source may end with “*” to match any path with
+// that prefix; then “:splat” in the destination is replaced with the matched
+// part.
+//
+// Returned map is suitable to be used as Checker.Redirects.
+func ParseRedirects(r io.Reader) (map[string]string, error) {
+	out := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("mdlinks: redirects line %d: want source and destination", n)
+		}
+		out[fields[0]] = fields[1]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// redirectTarget returns the destination for fsys path p according to
+// Checker.Redirects, and whether there's such a redirect. Returned
+// destination is either an fsys path or an absolute url.
+func (st *checkState) redirectTarget(p string) (string, bool) {
+	if len(st.c.Redirects) == 0 {
+		return "", false
+	}
+	dest, ok := st.c.Redirects["/"+p]
+	if !ok {
+		dest, ok = st.c.Redirects[p]
+	}
+	if !ok {
+		// the longest matching splat rule wins
+		var best string
+		for from, to := range st.c.Redirects {
+			prefix := strings.TrimPrefix(strings.TrimSuffix(from, "*"), "/")
+			if !strings.HasSuffix(from, "*") || len(prefix) < len(best) || !strings.HasPrefix(p, prefix) {
+				continue
+			}
+			best, dest, ok = prefix, strings.ReplaceAll(to, ":splat", p[len(prefix):]), true
+		}
+	}
+	if !ok {
+		return "", false
+	}
+	if isExternalLink(dest) {
+		return dest, true
+	}
+	return strings.TrimPrefix(path.Clean("/"+dest), "/"), true
+}
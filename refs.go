@@ -0,0 +1,132 @@
+package mdlinks
+
+import (
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+// refContext is a parser.Context that records link reference definitions
+// looked up by the parser while resolving reference-style links.
+type refContext struct {
+	parser.Context
+	used    map[string]bool // normalized labels of found definitions
+	missing map[string]bool // normalized labels that have no definitions
+}
+
+func newRefContext() *refContext {
+	return &refContext{
+		Context: parser.NewContext(),
+		used:    make(map[string]bool),
+		missing: make(map[string]bool),
+	}
+}
+
+func (pc *refContext) Reference(label string) (parser.Reference, bool) {
+	ref, ok := pc.Context.Reference(label)
+	if ok {
+		pc.used[label] = true
+	} else {
+		pc.missing[label] = true
+	}
+	return ref, ok
+}
+
+// refDefRe matches the beginning of link reference definitions, like
+// “[label]: ”, possibly nested in block quotes or list items.
+var refDefRe = regexp.MustCompile(`(?m)^[ \t>]*(?:(?:[-*+]|\d{1,9}[.)])[ \t]+)?\[((?:[^\[\]\\]|\\.)+)\]:`)
+
+// unusedReferences returns link reference definitions found by the parser
+// that were never used, in the document order. Raw of each returned link is
+// the definition label. Matches inside code, see codeRanges, are skipped.
+func unusedReferences(body []byte, pc *refContext, code []int) []LinkInfo {
+	unused := make(map[string]bool)
+	for _, ref := range pc.References() {
+		if key := util.ToLinkReference(ref.Label()); !pc.used[key] {
+			unused[key] = true
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	var out []LinkInfo
+	for _, m := range refDefRe.FindAllSubmatchIndex(body, -1) {
+		label := body[m[2]:m[3]]
+		key := util.ToLinkReference(label)
+		if !unused[key] || inRanges(code, m[2]) {
+			continue
+		}
+		delete(unused, key)
+		l := LinkInfo{Raw: string(label)}
+		l.setOffset(body, m[2])
+		l.LineStart, l.LineEnd = l.Line, l.Line
+		out = append(out, l)
+	}
+	return out
+}
+
+// refUseRe matches full and collapsed reference-style links, like
+// “[text][label]” and “[label][]”; shortcut ones, like “[label]”, can't be
+// told apart from text in brackets.
+var refUseRe = regexp.MustCompile(`\[((?:[^\[\]\\]|\\.)*)\]\[((?:[^\[\]\\]|\\.)*)\]`)
+
+// danglingReferences returns full and collapsed reference-style links that
+// the parser failed to resolve because their definitions are missing, in the
+// document order. Raw of each returned link is the reference label. Matches
+// inside code, see codeRanges, are skipped.
+func danglingReferences(body []byte, pc *refContext, code []int) []LinkInfo {
+	if len(pc.missing) == 0 {
+		return nil
+	}
+	var out []LinkInfo
+	for _, m := range refUseRe.FindAllSubmatchIndex(body, -1) {
+		start, end := m[4], m[5]
+		if start == end { // collapsed
+			start, end = m[2], m[3]
+		}
+		label := body[start:end]
+		if len(label) == 0 || label[0] == '^' { // footnotes are handled elsewhere
+			continue
+		}
+		if !pc.missing[util.ToLinkReference(label)] || inRanges(code, start) {
+			continue
+		}
+		l := LinkInfo{Raw: string(label)}
+		l.setOffset(body, start)
+		l.LineStart, l.LineEnd = l.Line, l.Line
+		out = append(out, l)
+	}
+	return out
+}
+
+// codeRanges returns byte ranges of the lines of code blocks and of the code
+// spans of the parsed document node, as pairs of start and end offsets, see
+// inRanges. Their content can't have links or link reference definitions.
+func codeRanges(node ast.Node) []int {
+	var out []int
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				out = append(out, seg.Start, seg.Stop)
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					out = append(out, t.Segment.Start, t.Segment.Stop)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
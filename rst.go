@@ -0,0 +1,286 @@
+package mdlinks
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// isRST reports whether p is a reStructuredText document. Such documents
+// matched by Checker.Matcher are parsed with extractRSTDetails instead of the
+// markdown parser.
+func isRST(p string) bool { return strings.EqualFold(path.Ext(p), ".rst") }
+
+var (
+	// rstLabelRe matches internal hyperlink targets, like “.. _install:”,
+	// and hyperlink targets with destinations, like “.. _docs: guide.rst”.
+	rstLabelRe = regexp.MustCompile(`^\.\.[ \t]+_([^:` + "`" + `]+|` + "`[^`]+`" + `):(?:[ \t]+(\S+))?[ \t]*$`)
+
+	// rstDirectiveRe matches directives with file arguments, like
+	// “.. image:: diagram.png”.
+	rstDirectiveRe = regexp.MustCompile(`^[ \t]*\.\.[ \t]+(image|figure|include|literalinclude)::[ \t]+(\S+)`)
+
+	// rstCodeDirectiveRe matches directives which content is literal.
+	rstCodeDirectiveRe = regexp.MustCompile(`^[ \t]*\.\.[ \t]+(?:code-block|code|sourcecode)::`)
+
+	// rstRoleRe matches “:doc:”, “:ref:”, and “:download:” roles, like
+	// “:doc:`guide`” or “:ref:`Title <label>`”.
+	rstRoleRe = regexp.MustCompile(`:(doc|ref|download):` + "`([^`]+)`")
+
+	// rstInlineLinkRe matches embedded URIs, like “`text <guide.html>`_”.
+	rstInlineLinkRe = regexp.MustCompile("`[^`<]*<([^`<>]+)>`__?")
+
+	// rstLiteralRe matches inline literals, like “``code``”.
+	rstLiteralRe = regexp.MustCompile("``[^`]+``")
+)
+
+// extractRSTDetails parses reStructuredText document body, returning targets
+// of its “:doc:” and “:download:” roles, embedded URIs, hyperlink targets,
+// and file arguments of image, figure, and include directives, as links;
+// “:ref:” roles as labels to be looked up in the whole project; and ids of
+// sections and labels as anchors, generated the way docutils does it.
+func extractRSTDetails(body []byte) *docDetails {
+	d := &docDetails{anchors: make(map[string]struct{})}
+	addAnchor := func(id string, off, level int, text string) {
+		if id == "" {
+			return
+		}
+		d.anchors[id] = struct{}{}
+		a := Anchor{ID: id, Level: level, Text: text, Offset: off}
+		a.Line, a.Column = textPosition(body, off)
+		d.anchorList = append(d.anchorList, a)
+	}
+	addLink := func(raw, p string, off int) {
+		l := LinkInfo{Raw: raw}
+		l.setOffset(body, off)
+		l.LineStart, l.LineEnd = l.Line, l.Line
+		switch {
+		case isExternalLink(raw):
+			d.external = append(d.external, l)
+		case p != "":
+			l.Path = p
+			d.links = append(d.links, l)
+		default:
+			if u := localLink(raw); u != nil {
+				l.Path, l.Fragment = u.Path, u.Fragment
+				d.links = append(d.links, l)
+			}
+		}
+		d.all = append(d.all, l)
+	}
+	lines := bytes.SplitAfter(body, []byte{'\n'})
+	offsets := make([]int, len(lines))
+	for i, off := 1, 0; i < len(lines); i++ {
+		off += len(lines[i-1])
+		offsets[i] = off
+	}
+	text := func(i int) []byte { return bytes.TrimRight(lines[i], " \t\r\n") }
+	var styles []string // section adornment styles in the order of appearance
+	literal := -1       // indentation of the line starting the literal block
+	for i := range lines {
+		line := text(i)
+		indent := len(line) - len(bytes.TrimLeft(line, " \t"))
+		if literal >= 0 {
+			if len(line) == 0 || indent > literal {
+				continue
+			}
+			literal = -1
+		}
+		lineOff := offsets[i]
+		// paragraphs ending with “::” are followed by literal blocks, unlike
+		// most directives, like “.. note::”
+		if rstCodeDirectiveRe.Match(line) ||
+			bytes.HasSuffix(line, []byte("::")) && !bytes.HasPrefix(line[indent:], []byte("..")) {
+			literal = indent
+		}
+		if isRSTTitle(lines, i) {
+			style := string(text(i + 1)[:1])
+			if i > 0 && isRSTAdornment(text(i-1)) {
+				style += "^" // with overline
+			}
+			level := 1
+			for level <= len(styles) && styles[level-1] != style {
+				level++
+			}
+			if level > len(styles) {
+				styles = append(styles, style)
+			}
+			title := strings.TrimSpace(string(line))
+			addAnchor(rstID(title), lineOff+indent, level, title)
+		}
+		if m := rstLabelRe.FindSubmatchIndex(line); m != nil {
+			name := strings.Trim(string(line[m[2]:m[3]]), "`")
+			if m[4] < 0 {
+				d.labels = append(d.labels, rstLabelName(name))
+				addAnchor(rstID(name), lineOff+m[2], 0, "")
+			} else if dest := string(line[m[4]:m[5]]); !strings.HasSuffix(dest, "_") {
+				addLink(dest, "", lineOff+m[4])
+			}
+			continue
+		}
+		if m := rstDirectiveRe.FindSubmatchIndex(line); m != nil {
+			addLink(string(line[m[4]:m[5]]), "", lineOff+m[4])
+			continue
+		}
+		// blank inline literals, keeping offsets
+		line = rstLiteralRe.ReplaceAllFunc(line, func(b []byte) []byte { return bytes.Repeat([]byte{' '}, len(b)) })
+		for _, m := range rstRoleRe.FindAllSubmatchIndex(line, -1) {
+			role := string(line[m[2]:m[3]])
+			start, end := m[4], m[5]
+			// “Title <target>” form
+			if content := line[start:end]; bytes.HasSuffix(content, []byte(">")) {
+				if j := bytes.LastIndexByte(content, '<'); j >= 0 {
+					start, end = start+j+1, end-1
+				}
+			}
+			start += len(line[start:end]) - len(bytes.TrimLeft(line[start:end], "~!"))
+			raw := string(line[start:end])
+			if raw == "" || strings.Contains(raw, "{") {
+				continue
+			}
+			switch role {
+			case "ref":
+				l := LinkInfo{Raw: raw}
+				l.setOffset(body, lineOff+start)
+				l.LineStart, l.LineEnd = l.Line, l.Line
+				d.rstRefs = append(d.rstRefs, l)
+			case "doc":
+				p := raw
+				if path.Ext(p) == "" {
+					p += ".rst"
+				}
+				addLink(raw, p, lineOff+start)
+			default:
+				addLink(raw, raw, lineOff+start)
+			}
+		}
+		for _, m := range rstInlineLinkRe.FindAllSubmatchIndex(line, -1) {
+			if dest := string(line[m[2]:m[3]]); !strings.HasSuffix(dest, "_") {
+				addLink(dest, "", lineOff+m[2])
+			}
+		}
+	}
+	return d
+}
+
+// isRSTTitle reports whether lines[i] is a section title, underlined with
+// the adornment not shorter than the title.
+func isRSTTitle(lines [][]byte, i int) bool {
+	if i+1 >= len(lines) {
+		return false
+	}
+	title := bytes.TrimRight(lines[i], " \t\r\n")
+	if len(bytes.TrimSpace(title)) == 0 || title[0] == ' ' || title[0] == '\t' || isRSTAdornment(title) {
+		return false
+	}
+	under := bytes.TrimRight(lines[i+1], " \t\r\n")
+	return isRSTAdornment(under) && len(under) >= utf8.RuneCount(title)
+}
+
+// isRSTAdornment reports whether line is a section title underline or
+// overline, like “=====”: a repeated ASCII punctuation character.
+func isRSTAdornment(line []byte) bool {
+	if len(line) < 2 || line[0] >= utf8.RuneSelf || !unicode.IsPunct(rune(line[0])) && !unicode.IsSymbol(rune(line[0])) {
+		return false
+	}
+	for _, c := range line[1:] {
+		if c != line[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// rstID returns the id docutils generates for the section title or the label
+// name: ASCII-only lowercased text, with sequences of other characters
+// replaced with hyphens, and leading digits and hyphens removed.
+func rstID(s string) string {
+	var b strings.Builder
+	var hyphen bool
+	for _, r := range norm.NFKD.String(strings.ToLower(s)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() != 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		case r < utf8.RuneSelf:
+			hyphen = true
+		}
+	}
+	return strings.TrimLeft(b.String(), "0123456789-")
+}
+
+// rstLabelName normalizes the label name the way Sphinx does it: lowercased,
+// with whitespace collapsed.
+func rstLabelName(s string) string { return strings.Join(strings.Fields(strings.ToLower(s)), " ") }
+
+// rstLabels returns labels defined in all reStructuredText documents of the
+// project. On the first call it parses all such documents.
+func (st *checkState) rstLabels() (map[string]struct{}, error) {
+	st.mu.Lock()
+	labels := st.labelIndex
+	st.mu.Unlock()
+	if labels != nil {
+		return labels, nil
+	}
+	labels = make(map[string]struct{})
+	add := func(p string) error {
+		if !isRST(p) {
+			return nil
+		}
+		docMeta, err := st.fileMeta(p)
+		if err != nil {
+			return err
+		}
+		for _, l := range docMeta.labels {
+			labels[l] = struct{}{}
+		}
+		return nil
+	}
+	if err := st.c.walk(st.fsys, add); err != nil {
+		return nil, err
+	}
+	if st.virtual != "" {
+		if err := add(st.virtual); err != nil {
+			return nil, err
+		}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.labelIndex == nil {
+		st.labelIndex = labels
+	}
+	return st.labelIndex, nil
+}
+
+// checkRSTRefs checks that “:ref:” roles of the document p refer to labels
+// defined in the project.
+func (st *checkState) checkRSTRefs(p string, docMeta *docDetails) ([]BrokenLink, error) {
+	var out []BrokenLink
+	for _, s := range docMeta.rstRefs {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		labels, err := st.rstLabels()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := labels[rstLabelName(s.Raw)]; ok {
+			continue
+		}
+		out = append(out, BrokenLink{
+			File:       p,
+			Link:       s,
+			Suggestion: suggestAnchor(s.Raw, rstLabelName(s.Raw), labels),
+			kind:       kindMissingLabel,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,200 @@
+package mdlinks
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Session checks documents of the file system repeatedly, as its files
+// change, keeping details of parsed documents between checks, so that only
+// changed documents are parsed again. It is meant for tools watching the
+// file system, like editors or “mdlinks -watch”.
+//
+// Session is not safe for concurrent use, and its Checker must not be
+// changed while the Session is in use.
+type Session struct {
+	st *checkState
+}
+
+// NewSession returns a Session checking documents of fsys.
+func (c *Checker) NewSession(fsys fs.FS) (*Session, error) {
+	if c == nil {
+		panic("mdlinks: NewSession called on a nil Checker")
+	}
+	if c.Matcher == nil {
+		panic("mdlinks: NewSession called with a nil Checker.Matcher")
+	}
+	st, err := c.newCheckState(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{st: st}, nil
+}
+
+// Check checks all documents the way Checker.CheckFS does. It returns paths
+// of checked documents, in traversal order, and broken links found.
+func (s *Session) Check() ([]string, []BrokenLink, error) {
+	s.st.external = nil
+	return s.st.checkAll()
+}
+
+// Update forgets cached details of the changed files, named by their fsys
+// paths, which may have been modified, created, or deleted. It then checks
+// the affected documents: changed documents matched by Checker.Matcher, and
+// documents linking to any of the changed files with links of any kind,
+// including wiki links, Hugo shortcodes, Jekyll tags, and reStructuredText
+// “:ref:” roles. It returns paths of checked documents, in traversal order,
+// and broken links found in them. Checks of the whole project, like the
+// MkDocs nav ones, are skipped.
+func (s *Session) Update(changed ...string) ([]string, []BrokenLink, error) {
+	st := s.st
+	files, err := s.Affected(changed...)
+	if err != nil {
+		return nil, nil, err
+	}
+	st.external = nil
+	var out []BrokenLink
+	for _, p := range files {
+		links, err := st.checkFile(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, links...)
+	}
+	if len(st.external) != 0 {
+		fileOrder := make(map[string]int, len(files))
+		for i, p := range files {
+			fileOrder[p] = i
+		}
+		out = append(out, st.c.checkExternal(st.external)...)
+		sort.SliceStable(out, func(i, j int) bool { return fileOrder[out[i].File] < fileOrder[out[j].File] })
+	}
+	return files, out, nil
+}
+
+// Affected forgets cached details of the changed files the way Update does,
+// and returns paths of documents Update would check, in traversal order,
+// without checking them. It lets callers check only some of the affected
+// documents, like the ones open in an editor.
+func (s *Session) Affected(changed ...string) ([]string, error) {
+	st := s.st
+	st.forget(changed)
+	isChanged := make(map[string]bool, len(changed))
+	var removed, labelsChanged bool
+	for _, p := range changed {
+		isChanged[p] = true
+		removed = removed || !st.exists(p)
+		labelsChanged = labelsChanged || isRST(p)
+	}
+	var files []string
+	err := st.c.walk(st.fsys, func(p string) error {
+		if isChanged[p] {
+			files = append(files, p)
+			return nil
+		}
+		docMeta, err := st.fileMeta(p)
+		if err != nil {
+			return err
+		}
+		switch dependent, err := st.dependsOn(p, docMeta, isChanged, removed, labelsChanged); {
+		case err != nil:
+			return err
+		case dependent:
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// dependsOn reports whether results of the document p, with details docMeta,
+// may change with the changed files: whether any of its links, of any kind,
+// points to one of them, or to the index document of a directory. Links that
+// don't resolve to any existing file, like wiki links to pages found by name,
+// or links with inferred extensions, may have pointed to a file that was
+// removed. Likewise, reStructuredText “:ref:”
+// roles may refer to labels of any changed reStructuredText document.
+func (st *checkState) dependsOn(p string, docMeta *docDetails, isChanged map[string]bool, removed, labelsChanged bool) (bool, error) {
+	// the index document may be deleted, so that indexDocument doesn't
+	// find it any more
+	changedTarget := func(target string) bool {
+		if isChanged[target] {
+			return true
+		}
+		for _, name := range st.c.DirectoryIndex {
+			if isChanged[path.Join(target, name)] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, l := range docMeta.links {
+		if st.ignored(l.Raw) {
+			continue
+		}
+		target, err := st.resolve(p, l)
+		if err != nil {
+			return false, err
+		}
+		// targets found by rules like InferExtensions resolve literally once
+		// the file is removed, so they no longer match the changed paths
+		if target != "" && removed && !st.exists(target) || changedTarget(target) {
+			return true, nil
+		}
+	}
+	for _, l := range docMeta.wiki {
+		if st.ignored(l.Raw) || l.Path == "" {
+			continue
+		}
+		target, err := st.resolveWikiLink(p, l.Path)
+		if err != nil {
+			return false, err
+		}
+		if target == "" && removed || changedTarget(target) {
+			return true, nil
+		}
+	}
+	for _, l := range docMeta.hugo {
+		if st.ignored(l.Raw) || l.Path == "" {
+			continue
+		}
+		target := st.resolveHugoRef(p, l.Path)
+		if target == "" && removed || changedTarget(target) {
+			return true, nil
+		}
+	}
+	for _, l := range docMeta.jekyll {
+		if st.ignored(l.Raw) {
+			continue
+		}
+		target := strings.TrimPrefix(path.Clean(l.Path), "/")
+		if l.post {
+			var err error
+			if target, err = st.resolveJekyllPost(l.Path); err != nil {
+				return false, err
+			}
+		}
+		if target == "" && removed || isChanged[target] {
+			return true, nil
+		}
+	}
+	return labelsChanged && len(docMeta.rstRefs) != 0, nil
+}
+
+// forget drops cached details of the named files, and indexes built from
+// the list of files, so that they're built again as needed.
+func (st *checkState) forget(names []string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, p := range names {
+		delete(st.seen, p)
+		delete(st.seenHTML, p)
+	}
+	st.nameIndex, st.nfcIndex, st.dirNames, st.slugIndex = nil, nil, nil, nil
+	st.labelIndex, st.goDeclIndex, st.lineCounts = nil, nil, nil
+}
@@ -0,0 +1,227 @@
+package mdlinks
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SlugAlgorithm selects the rules of generating header slugs, see Slugify.
+type SlugAlgorithm int
+
+const (
+	// SlugDefault is the algorithm CheckFS uses by default. It follows
+	// similar, but not exactly matching, rules as GitHub: letters and
+	// digits are lowercased, spaces become dashes, dashes and underscores
+	// are kept, and everything else is dropped.
+	SlugDefault SlugAlgorithm = iota
+
+	// SlugGitHub follows the rules of GitHub and its github-slugger
+	// library: text is lowercased, spaces become dashes, and all characters
+	// except letters, combining marks, digits, dashes, and connector
+	// punctuation, like underscores, are dropped. Unlike SlugDefault, it
+	// keeps combining marks of non-Latin scripts, and only converts regular
+	// spaces, but not other whitespace.
+	SlugGitHub
+
+	// SlugGitLab follows the rules of GitLab: text is lowercased,
+	// everything except letters, combining marks, digits, underscores,
+	// dashes, and spaces is dropped, spaces become dashes, and repeated
+	// dashes are collapsed into one. Slugs made only of digits get the
+	// “anchor-” prefix, like “anchor-2024”.
+	SlugGitLab
+
+	// SlugAzureDevOps follows the rules of Azure DevOps wikis: text is
+	// lowercased, spaces become dashes, and all other characters, except
+	// ASCII letters, digits, and “_.~”, are percent-encoded, so that
+	// dashes of the text become “%2D”.
+	SlugAzureDevOps
+
+	// SlugKramdown follows the rules of kramdown auto_ids, used by Jekyll:
+	// leading characters other than ASCII letters are dropped, then
+	// everything except ASCII letters, digits, spaces, and dashes is
+	// dropped, spaces become dashes, and text is lowercased. Empty slugs
+	// become “section”.
+	SlugKramdown
+
+	// SlugVSCode follows the rules of the Visual Studio Code markdown
+	// preview: text is trimmed and lowercased, runs of whitespace become
+	// single dashes, ASCII and common CJK punctuation is dropped, leading
+	// and trailing dashes are trimmed, and the result is percent-encoded
+	// like JavaScript encodeURI does.
+	SlugVSCode
+
+	// SlugPandoc follows the rules of the Pandoc auto_identifiers
+	// extension: everything except letters, digits, whitespace,
+	// underscores, dashes, and periods is dropped, whitespace becomes
+	// dashes, text is lowercased, and everything up to the first letter is
+	// dropped. Empty slugs become “section”.
+	SlugPandoc
+
+	// SlugPandocGFM follows the rules of the Pandoc gfm_auto_identifiers
+	// extension: text is lowercased, everything except letters, digits,
+	// spaces, underscores, and dashes is dropped, and spaces become dashes.
+	SlugPandocGFM
+)
+
+// Slugify returns the slug of the header text generated by the algorithm
+// algo. Text is the plain header text, without markdown formatting. Slugs of
+// repeated headers need numeric suffixes, like “-1”, which Slugify doesn't
+// add.
+func Slugify(text string, algo SlugAlgorithm) string {
+	switch algo {
+	case SlugGitHub:
+		return githubSlug(text)
+	case SlugGitLab:
+		return gitlabSlug(text)
+	case SlugAzureDevOps:
+		return azureSlug(text)
+	case SlugKramdown:
+		return kramdownSlug(text)
+	case SlugVSCode:
+		return vscodeSlug(text)
+	case SlugPandoc:
+		return pandocSlug(text)
+	case SlugPandocGFM:
+		return pandocGFMSlug(text)
+	}
+	return slugify(text)
+}
+
+func slugify(text string) string {
+	f := func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r):
+			return '-'
+		case r == '-' || r == '_':
+			return r
+		}
+		return -1
+	}
+	return strings.Map(f, text)
+}
+
+func githubSlug(text string) string {
+	f := func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-' || unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.Pc):
+			return r
+		}
+		return -1
+	}
+	return strings.Map(f, strings.ToLower(text))
+}
+
+func gitlabSlug(text string) string {
+	f := func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-' || unicode.In(r, unicode.L, unicode.M, unicode.Nd, unicode.Pc):
+			return r
+		}
+		return -1
+	}
+	s := strings.Map(f, strings.ToLower(text))
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "-")
+	}
+	if s != "" && strings.Trim(s, "0123456789") == "" {
+		// digit-only anchors would conflict with references to issues
+		s = "anchor-" + s
+	}
+	return s
+}
+
+func azureSlug(text string) string {
+	var b strings.Builder
+	for _, c := range []byte(strings.ToLower(text)) {
+		switch {
+		case c == ' ':
+			b.WriteByte('-')
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func kramdownSlug(text string) string {
+	text = strings.TrimLeftFunc(text, func(r rune) bool { return !isASCIILetter(r) })
+	f := func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-' || isASCIILetter(r) || '0' <= r && r <= '9':
+			return unicode.ToLower(r)
+		}
+		return -1
+	}
+	if s := strings.Map(f, text); s != "" {
+		return s
+	}
+	return "section"
+}
+
+func isASCIILetter(r rune) bool { return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' }
+
+// vscodePunctuation lists characters dropped by the VS Code slugifier.
+const vscodePunctuation = "][!/'\"#$%&()*+,.:;<=>?@\\^{|}~`" +
+	"。，、；：？！…—·ˉ¨‘’“”々～‖∶＂＇｀｜〃〔〕〈〉《》「」『』．〖〗【】（）［］｛｝"
+
+func vscodeSlug(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), "-")
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(vscodePunctuation, r) {
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.Trim(text, "-")
+	// encodeURI
+	var b strings.Builder
+	for _, c := range []byte(text) {
+		if c < 0x80 && (isASCIILetter(rune(c)) || '0' <= c && c <= '9' || strings.IndexByte(";,/?:@&=+$-_.!~*'()#", c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func pandocSlug(text string) string {
+	f := func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return '-'
+		case r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			return unicode.ToLower(r)
+		}
+		return -1
+	}
+	s := strings.TrimLeftFunc(strings.Map(f, text), func(r rune) bool { return !unicode.IsLetter(r) })
+	if s == "" {
+		return "section"
+	}
+	return s
+}
+
+func pandocGFMSlug(text string) string {
+	f := func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			return r
+		}
+		return -1
+	}
+	return strings.Map(f, strings.ToLower(text))
+}
@@ -0,0 +1,161 @@
+package mdlinks
+
+import (
+	"path"
+	"strings"
+)
+
+// suggestFile returns a replacement for the raw link from the document p
+// whose target, fsys path target, doesn't exist. It looks for files with the
+// same base name elsewhere in the tree, and for files with similar base
+// names. It returns an empty string if there's no single best candidate.
+func (st *checkState) suggestFile(p, raw, target string) (string, error) {
+	if _, err := st.filesNamed(""); err != nil { // make sure index is built
+		return "", err
+	}
+	base := path.Base(target)
+	maxDist := len([]rune(base)) / 3
+	if maxDist > 3 {
+		maxDist = 3
+	}
+	var best string
+	var bestDist, bestPathDist int
+	var ambiguous bool
+	for name, paths := range st.nameIndex {
+		d := editDistance(base, name)
+		if d > maxDist || (best != "" && d > bestDist) {
+			continue
+		}
+		for _, cand := range paths {
+			if cand == p {
+				continue
+			}
+			pd := editDistance(target, cand)
+			if strings.HasSuffix(cand, "/"+target) {
+				pd = 0 // same path under another directory
+			}
+			switch {
+			case best == "" || d < bestDist || (d == bestDist && pd < bestPathDist):
+				best, bestDist, bestPathDist, ambiguous = cand, d, pd, false
+			case d == bestDist && pd == bestPathDist:
+				ambiguous = true
+			}
+		}
+	}
+	if best == "" || ambiguous {
+		return "", nil
+	}
+	return st.replaceLinkPath(raw, p, best), nil
+}
+
+// suggestAnchor returns a replacement for the raw link whose fragment is not
+// found among anchors, pointing to the closest existing anchor instead. It
+// returns an empty string if there's no single close enough anchor.
+func suggestAnchor(raw, fragment string, anchors map[string]struct{}) string {
+	maxDist := len([]rune(fragment)) / 3
+	switch {
+	case maxDist < 1:
+		maxDist = 1
+	case maxDist > 3:
+		maxDist = 3
+	}
+	var best string
+	var bestDist int
+	var ambiguous bool
+	for a := range anchors {
+		switch d := editDistance(fragment, a); {
+		case d > maxDist:
+		case best == "" || d < bestDist:
+			best, bestDist, ambiguous = a, d, false
+		case d == bestDist:
+			ambiguous = true
+		}
+	}
+	if best == "" || ambiguous {
+		return ""
+	}
+	i := strings.IndexByte(raw, '#')
+	if i < 0 { // like AsciiDoc “xref:id[]”, where the whole link is a fragment
+		return best
+	}
+	return raw[:i+1] + best
+}
+
+// replaceLinkPath returns the raw link from the document p with its path
+// replaced with the one pointing to fsys path target, keeping query and
+// fragment. Links that were absolute stay absolute, and the style of the
+// original link, like the “./” prefix or the trailing slash, is kept.
+func replaceLinkPath(raw, p, target string) string {
+	var suffix string
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw, suffix = raw[:i], raw[i:]
+	}
+	if strings.HasSuffix(raw, "/") {
+		suffix = "/" + suffix
+	}
+	target = linkPathEscaper.Replace(target)
+	if strings.HasPrefix(raw, "/") {
+		return "/" + target + suffix
+	}
+	rel := relativePath(path.Dir(p), target)
+	if strings.HasPrefix(raw, "./") && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel + suffix
+}
+
+// linkPathEscaper escapes characters of file names that can't be used in
+// link destinations as is.
+var linkPathEscaper = strings.NewReplacer("%", "%25", " ", "%20")
+
+// relativePath returns /-separated path to target relative to the directory
+// dir; both are fsys paths.
+func relativePath(dir, target string) string {
+	if dir == "." {
+		return target
+	}
+	from := strings.Split(dir, "/")
+	to := strings.Split(target, "/")
+	var i int
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	parts := make([]string, 0, len(from)-i+len(to)-i)
+	for range from[i:] {
+		parts = append(parts, "..")
+	}
+	return strings.Join(append(parts, to[i:]...), "/")
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in
+// runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,181 @@
+package mdlinks
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// WikiLinkMode selects whether and how wiki-style links, like “[[Page]]”, and
+// embeds, like “![[image.png]]” or “![[Page#Heading]]”, are checked.
+type WikiLinkMode byte
+
+const (
+	// WikiLinksDisabled treats wiki-style links as plain text.
+	WikiLinksDisabled WikiLinkMode = iota
+
+	// WikiLinksSameDir resolves “[[Page]]” to the “Page.md” file in the
+	// directory of the linking document; “[[dir/Page]]” is resolved
+	// relative to the same directory.
+	WikiLinksSameDir
+
+	// WikiLinksShortestPath resolves “[[Page]]” to the “Page.md” file
+	// anywhere in the filesystem, like Obsidian does: if there are
+	// multiple such files, the one in the directory of the linking document
+	// is preferred, then the one with the shortest path. Targets with
+	// slashes, like “[[dir/Page]]”, are relative to the filesystem root.
+	WikiLinksShortestPath
+)
+
+var kindWikiLink = ast.NewNodeKind("WikiLink")
+
+// wikiLink is an inline node for “[[target|label]]” links and
+// “![[target]]” embeds.
+type wikiLink struct {
+	ast.BaseInline
+	Raw    []byte // whole link as seen in the source
+	Target []byte // link target, without label
+	Embed  bool
+	Offset int // byte offset of Raw in the source
+}
+
+func (n *wikiLink) Kind() ast.NodeKind { return kindWikiLink }
+
+func (n *wikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": string(n.Target)}, nil)
+}
+
+// wikiLinkParser is a goldmark inline parser for wiki-style links.
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte { return []byte{'[', '!'} }
+
+func (wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, seg := block.PeekLine()
+	var prefix int // length of the “!” prefix of embeds
+	if bytes.HasPrefix(line, []byte("!")) {
+		prefix = 1
+	}
+	if !bytes.HasPrefix(line[prefix:], []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[prefix+2:], []byte("]]"))
+	if end <= 0 {
+		return nil
+	}
+	inner := line[prefix+2 : prefix+2+end]
+	if bytes.ContainsAny(inner, "[]") {
+		return nil
+	}
+	target, label := inner, inner
+	labelStart := seg.Start + prefix + 2
+	if i := bytes.IndexByte(inner, '|'); i >= 0 {
+		target, label = inner[:i], inner[i+1:]
+		labelStart += i + 1
+	}
+	target = bytes.TrimSpace(target)
+	if len(target) == 0 {
+		return nil
+	}
+	size := prefix + 2 + end + 2
+	node := &wikiLink{Raw: line[:size], Target: target, Embed: prefix != 0, Offset: seg.Start}
+	if len(label) != 0 && !node.Embed {
+		node.AppendChild(node, ast.NewTextSegment(text.NewSegment(labelStart, labelStart+len(label))))
+	}
+	block.Advance(size)
+	return node
+}
+
+// splitWikiTarget splits wiki link target into the page and heading parts.
+func splitWikiTarget(s string) (page, heading string) {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+// wikiTargetFile returns a file name wiki link page name refers to: names
+// without an extension refer to markdown documents.
+func wikiTargetFile(page string) string {
+	if path.Ext(page) == "" {
+		return page + ".md"
+	}
+	return page
+}
+
+// resolveWikiLink returns fsys path the wiki link page, found in document p,
+// points to, or an empty string if it cannot be found.
+func (st *checkState) resolveWikiLink(p, page string) (string, error) {
+	name := wikiTargetFile(page)
+	switch {
+	case st.c.WikiLinks == WikiLinksSameDir:
+		return path.Join(path.Dir(p), name), nil
+	case strings.Contains(name, "/"):
+		return strings.TrimPrefix(path.Clean(name), "/"), nil
+	}
+	cands, err := st.filesNamed(path.Base(name))
+	if err != nil || len(cands) == 0 {
+		return "", err
+	}
+	best := cands[0]
+	for _, c := range cands {
+		if path.Dir(c) == path.Dir(p) {
+			return c, nil
+		}
+		if len(c) < len(best) {
+			best = c
+		}
+	}
+	return best, nil
+}
+
+// checkWikiLinks checks wiki-style links of the document p.
+func (st *checkState) checkWikiLinks(p string, docMeta *docDetails) ([]BrokenLink, error) {
+	var out []BrokenLink
+	for _, s := range docMeta.wiki {
+		if st.ignored(s.Raw) {
+			continue
+		}
+		meta, anchorsOf := docMeta, p
+		if s.Path != "" {
+			target, err := st.resolveWikiLink(p, s.Path)
+			if err != nil {
+				return nil, err
+			}
+			if target == "" || !st.exists(target) {
+				out = append(out, BrokenLink{File: p, Link: s})
+				continue
+			}
+			if s.Fragment == "" {
+				continue
+			}
+			if meta, err = st.targetMeta(target); err != nil {
+				return nil, err
+			}
+			anchorsOf = st.indexDocument(target)
+		}
+		// “#^id” fragments reference blocks, not headings
+		if meta == nil || s.Fragment == "" || s.Fragment[0] == '^' {
+			continue
+		}
+		if _, ok := meta.anchors[st.opts.slug(s.Fragment)]; ok {
+			continue
+		}
+		switch valid, err := st.fragmentValid(anchorsOf, s.Fragment); {
+		case err != nil:
+			return nil, err
+		case valid:
+			continue
+		}
+		kind := kindBrokenExternalAnchor
+		if s.Path == "" {
+			kind = kindBrokenInternalAnchor
+		}
+		out = append(out, BrokenLink{File: p, Link: s, kind: kind})
+	}
+	return out, nil
+}